@@ -18,8 +18,12 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
+	"syscall"
 
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/exec"
 )
 
 // AuthAdd will create a new user with the given capabilities and using the already generated keyring
@@ -72,6 +76,57 @@ func AuthGetOrCreateKey(context *clusterd.Context, clusterName, name string, cap
 	return parseAuthKey(buf)
 }
 
+// AuthCreateKey creates a new user with the given capabilities and returns its key. The caps are
+// keyed by the daemon type they apply to, such as "mon": "profile rbd". An error is returned if the
+// user already exists, rather than returning the key of the existing user.
+func AuthCreateKey(context *clusterd.Context, clusterName, name string, caps map[string]string) (string, error) {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 || parts[1] == "" || !authEntityTypes[parts[0]] {
+		return "", fmt.Errorf("invalid auth entity name %s, expected <type>.<id> such as client.myuser", name)
+	}
+	if len(caps) == 0 {
+		return "", fmt.Errorf("no capabilities given for %s", name)
+	}
+
+	// pass the caps in a stable order
+	daemons := []string{}
+	for daemon, cap := range caps {
+		if !authCapTypes[daemon] {
+			return "", fmt.Errorf("invalid capability type %s for %s", daemon, name)
+		}
+		if strings.TrimSpace(cap) == "" {
+			return "", fmt.Errorf("empty %s capability for %s", daemon, name)
+		}
+		daemons = append(daemons, daemon)
+	}
+	sort.Strings(daemons)
+	capArgs := []string{}
+	for _, daemon := range daemons {
+		capArgs = append(capArgs, daemon, caps[daemon])
+	}
+
+	exists, err := AuthExists(context, clusterName, name)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("auth user %s already exists", name)
+	}
+
+	// the user may have been created since the check, in which case the mon fails the add with EEXIST
+	args := append([]string{"auth", "add", name}, capArgs...)
+	_, err = NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return "", fmt.Errorf("auth user %s already exists", name)
+		}
+		return "", fmt.Errorf("failed to auth add for %s: %+v", name, err)
+	}
+
+	return AuthGetKey(context, clusterName, name)
+}
+
 // AuthUpdateCaps updates the capabilities for the given user.
 func AuthUpdateCaps(context *clusterd.Context, clusterName, name string, caps []string) error {
 	args := append([]string{"auth", "caps", name}, caps...)
@@ -82,10 +137,72 @@ func AuthUpdateCaps(context *clusterd.Context, clusterName, name string, caps []
 	return err
 }
 
-// AuthDelete will delete the given user.
-func AuthDelete(context *clusterd.Context, clusterName, name string) error {
-	args := []string{"auth", "del", name}
+var (
+	authEntityTypes = map[string]bool{"client": true, "osd": true, "mon": true, "mgr": true, "mds": true}
+	authCapTypes    = map[string]bool{"mon": true, "osd": true, "mgr": true, "mds": true}
+
+	// the users that the operator and the mons depend on to manage the cluster
+	authProtectedEntities = map[string]bool{"client.admin": true, "mon.": true}
+)
+
+// AuthEntity is a ceph auth user and its capabilities. The secret key of the user is intentionally
+// not included.
+type AuthEntity struct {
+	Entity string            `json:"entity"`
+	Caps   map[string]string `json:"caps"`
+}
+
+// AuthList lists the ceph auth users and their capabilities, without their keys.
+func AuthList(context *clusterd.Context, clusterName string) ([]AuthEntity, error) {
+	args := []string{"auth", "ls"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auth users. %+v", err)
+	}
+
+	var resp struct {
+		AuthDump []AuthEntity `json:"auth_dump"`
+	}
+	// the raw response contains the keys of all users, so it is not included in the error
+	if err := json.Unmarshal(buf, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal auth list response: %+v", err)
+	}
+	return resp.AuthDump, nil
+}
+
+// AuthExists returns whether the given user exists.
+func AuthExists(context *clusterd.Context, clusterName, name string) (bool, error) {
+	args := []string{"auth", "get", name}
 	_, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get auth for %s. %v", name, err)
+	}
+	return true, nil
+}
+
+// AuthDelete will delete the given user. An error is returned if the user does not exist. The admin
+// and mon users are not deleted unless force is set since the cluster could no longer be managed
+// without them.
+func AuthDelete(context *clusterd.Context, clusterName, name string, force bool) error {
+	if authProtectedEntities[name] && !force {
+		return fmt.Errorf("refusing to delete auth for %s", name)
+	}
+
+	// 'auth del' succeeds for a user that does not exist, so check for the user first
+	exists, err := AuthExists(context, clusterName, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("entity %s does not exist", name)
+	}
+
+	args := []string{"auth", "del", name}
+	_, err = NewCephCommand(context, clusterName, args).Run()
 	if err != nil {
 		return fmt.Errorf("failed to delete auth for %s. %v", name, err)
 	}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthCreateKey(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	var createArgs []string
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "auth" && args[1] == "get":
+			if args[2] == "client.admin" {
+				return "", nil
+			}
+			return "", mockExitError(syscall.ENOENT)
+		case args[0] == "auth" && args[1] == "add":
+			if args[2] == "client.tenant-b" {
+				// another caller created the user after it was checked
+				return "", mockExitError(syscall.EEXIST)
+			}
+			createArgs = args
+			return "", nil
+		case args[0] == "auth" && args[1] == "get-key":
+			assert.Equal(t, "client.tenant-a", args[2])
+			return `{"key":"AQCvzWBeAAAAABAA5s8Rmtj5a4R5n9bUfAl4fA=="}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	key, err := AuthCreateKey(context, "rook", "client.tenant-a", map[string]string{"osd": "profile rbd pool=pool1", "mon": "profile rbd"})
+	assert.Nil(t, err)
+	assert.Equal(t, "AQCvzWBeAAAAABAA5s8Rmtj5a4R5n9bUfAl4fA==", key)
+	assert.Equal(t, []string{"client.tenant-a", "mon", "profile rbd", "osd", "profile rbd pool=pool1"}, createArgs[2:7])
+
+	createArgs = nil
+	_, err = AuthCreateKey(context, "rook", "client.admin", map[string]string{"mon": "allow r"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+	_, err = AuthCreateKey(context, "rook", "client.tenant-b", map[string]string{"mon": "allow r"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+	_, err = AuthCreateKey(context, "rook", "tenant-a", map[string]string{"mon": "allow r"})
+	assert.NotNil(t, err)
+	_, err = AuthCreateKey(context, "rook", "client.tenant-a", map[string]string{"disk": "allow r"})
+	assert.NotNil(t, err)
+	_, err = AuthCreateKey(context, "rook", "client.tenant-a", map[string]string{"mon": " "})
+	assert.NotNil(t, err)
+	assert.Nil(t, createArgs)
+}
+
+func TestAuthDelete(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	existing := map[string]bool{"osd.3": true, "client.admin": true}
+	deleted := []string{}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "auth" && args[1] == "get":
+			if !existing[args[2]] {
+				// the mon returns ENOENT for an entity that does not exist
+				return "", mockExitError(syscall.ENOENT)
+			}
+			return "", nil
+		case args[0] == "auth" && args[1] == "del":
+			// deleting an entity that does not exist succeeds
+			deleted = append(deleted, args[2])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	err := AuthDelete(context, "rook", "osd.3", false)
+	assert.Nil(t, err)
+	err = AuthDelete(context, "rook", "client.admin", false)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"osd.3"}, deleted)
+
+	err = AuthDelete(context, "rook", "osd.4", false)
+	assert.NotNil(t, err)
+	assert.Equal(t, "entity osd.4 does not exist", err.Error())
+	assert.Equal(t, []string{"osd.3"}, deleted)
+
+	// the protected entities can be deleted with force
+	err = AuthDelete(context, "rook", "client.admin", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"osd.3", "client.admin"}, deleted)
+
+	exists, err := AuthExists(context, "rook", "osd.4")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	exists, err = AuthExists(context, "rook", "osd.3")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+}
@@ -46,7 +46,8 @@ const (
 // FinalizeCephCommandArgs builds the command line to be called
 func FinalizeCephCommandArgs(command string, args []string, configDir, clusterName string) (string, []string) {
 	// the rbd client tool does not support the '--connect-timeout' option
-	// so we only use it for the 'ceph' command
+	// so we only use it for the 'ceph' command. The rbd commands that only read the state of an image
+	// are run with a timeout instead so that a hung mon cannot block them indefinitely.
 	// Also, there is no point of adding that option to 'crushtool' since that CLI does not connect to anything
 	// 'crushtool' is a utility that lets you create, compile, decompile and test CRUSH map files.
 
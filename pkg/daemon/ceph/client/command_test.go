@@ -17,11 +17,21 @@ limitations under the License.
 package client
 
 import (
+	"fmt"
+	osexec "os/exec"
+	"syscall"
 	"testing"
 
+	"github.com/rook/rook/pkg/util/exec"
 	"github.com/stretchr/testify/assert"
 )
 
+// mockExitError returns the error of a ceph command that exited with the given error number
+func mockExitError(errno syscall.Errno) error {
+	err := osexec.Command("sh", "-c", fmt.Sprintf("exit %d", int(errno))).Run()
+	return &exec.CommandError{ActionName: "ceph", Err: err}
+}
+
 func TestFinalizeCephCommandArgs(t *testing.T) {
 	RunAllCephCommandsInToolbox = false
 	clusterName := "rook"
@@ -0,0 +1,111 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+)
+
+const globalConfigSection = "global"
+
+// GetConfig returns the value of an option in the centralized config database of the mons for the
+// given entity, such as "global", "osd", "osd.3" or "client.rgw". The centralized config is only
+// available since mimic.
+func GetConfig(context *clusterd.Context, clusterName string, cephVersion cephver.CephVersion, who, key string) (string, error) {
+	if err := validateConfigArgs(cephVersion, who, key); err != nil {
+		return "", err
+	}
+	if who == globalConfigSection {
+		return getGlobalConfig(context, clusterName, key)
+	}
+
+	args := []string{"config", "get", who, key}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config %s for %s: %+v. %s", key, who, err, string(buf))
+	}
+
+	return parseConfigValue(buf), nil
+}
+
+// SetConfig sets an option in the centralized config database of the mons for the given entity and
+// returns the value it had before, so that the change can be audited or reverted.
+func SetConfig(context *clusterd.Context, clusterName string, cephVersion cephver.CephVersion, who, key, value string) (string, error) {
+	prevVal, err := GetConfig(context, clusterName, cephVersion, who, key)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"config", "set", who, key, value}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to set config %s for %s to \"%s\": %+v. %s", key, who, value, err, string(buf))
+	}
+
+	if prevVal != value {
+		logger.Infof("changed config %s for %s from \"%s\" to \"%s\"", key, who, prevVal, value)
+	}
+	return prevVal, nil
+}
+
+// the mons reject "global" as the entity of 'ceph config get', so global options are read from the
+// config dump instead. An option that is not set in the global section returns an empty value.
+func getGlobalConfig(context *clusterd.Context, clusterName, key string) (string, error) {
+	args := []string{"config", "dump"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to dump config: %+v. %s", err, string(buf))
+	}
+
+	var options []struct {
+		Section string `json:"section"`
+		Name    string `json:"name"`
+		Value   string `json:"value"`
+	}
+	if err := json.Unmarshal(buf, &options); err != nil {
+		return "", fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+	for _, option := range options {
+		if option.Section == globalConfigSection && option.Name == key {
+			return option.Value, nil
+		}
+	}
+	return "", nil
+}
+
+func validateConfigArgs(cephVersion cephver.CephVersion, who, key string) error {
+	if !cephVersion.IsAtLeastMimic() {
+		return fmt.Errorf("the centralized config is not supported on ceph version %s", cephVersion.String())
+	}
+	if who == "" || key == "" {
+		return fmt.Errorf("an entity and a key are required to access the config")
+	}
+	return nil
+}
+
+func parseConfigValue(buf []byte) string {
+	// the value is returned as a json string when the output is json, and as plain text otherwise
+	var value string
+	if err := json.Unmarshal(buf, &value); err == nil {
+		return value
+	}
+	return strings.TrimSpace(string(buf))
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfig(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	value := "1"
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "config" && args[1] == "get":
+			assert.Equal(t, "osd", args[2])
+			assert.Equal(t, "osd_max_backfills", args[3])
+			return value + "\n", nil
+		case args[0] == "config" && args[1] == "set":
+			value = args[4]
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	prev, err := SetConfig(context, "rook", cephver.Nautilus, "osd", "osd_max_backfills", "4")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", prev)
+	assert.Equal(t, "4", value)
+
+	current, err := GetConfig(context, "rook", cephver.Nautilus, "osd", "osd_max_backfills")
+	assert.Nil(t, err)
+	assert.Equal(t, "4", current)
+
+	_, err = SetConfig(context, "rook", cephver.Luminous, "osd", "osd_max_backfills", "2")
+	assert.NotNil(t, err)
+	_, err = GetConfig(context, "rook", cephver.Nautilus, "", "osd_max_backfills")
+	assert.NotNil(t, err)
+	assert.Equal(t, "4", value)
+
+	assert.Equal(t, "4", parseConfigValue([]byte(`"4"`)))
+}
+
+func TestSetGlobalConfig(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	var setArgs []string
+	dump := `[{"section":"global","name":"mon_max_pg_per_osd","value":"300","level":"advanced","can_update_at_runtime":true,"mask":""},` +
+		`{"section":"osd","name":"osd_max_backfills","value":"1","level":"advanced","can_update_at_runtime":true,"mask":""}]`
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "config" && args[1] == "dump":
+			return dump, nil
+		case args[0] == "config" && args[1] == "set":
+			setArgs = args
+			return "", nil
+		}
+		// the mons reject 'config get global'
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	prev, err := SetConfig(context, "rook", cephver.Nautilus, "global", "mon_max_pg_per_osd", "400")
+	assert.Nil(t, err)
+	assert.Equal(t, "300", prev)
+	assert.Equal(t, []string{"config", "set", "global", "mon_max_pg_per_osd", "400"}, setArgs[:5])
+
+	// only the global section is considered, and unset options have an empty previous value
+	prev, err = SetConfig(context, "rook", cephver.Nautilus, "global", "osd_max_backfills", "2")
+	assert.Nil(t, err)
+	assert.Equal(t, "", prev)
+}
@@ -180,6 +180,63 @@ func CrushRemove(context *clusterd.Context, clusterName, name string) (string, e
 	return string(buf), nil
 }
 
+// GetDeviceClasses returns the crush device classes of the cluster, such as hdd, ssd and nvme, with
+// the ids of the OSDs in each class.
+func GetDeviceClasses(context *clusterd.Context, clusterName string) (map[string][]int, error) {
+	args := []string{"osd", "crush", "class", "ls"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list crush device classes: %+v, %s", err, string(buf))
+	}
+
+	var classNames []string
+	if err := json.Unmarshal(buf, &classNames); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal crush device classes: %+v. raw: %s", err, string(buf))
+	}
+
+	classes := map[string][]int{}
+	for _, class := range classNames {
+		args := []string{"osd", "crush", "class", "ls-osd", class}
+		buf, err := NewCephCommand(context, clusterName, args).Run()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list osds of crush device class %s: %+v, %s", class, err, string(buf))
+		}
+
+		var osds []int
+		if err := json.Unmarshal(buf, &osds); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal osds of crush device class %s: %+v. raw: %s", class, err, string(buf))
+		}
+		classes[class] = osds
+	}
+
+	return classes, nil
+}
+
+// SetDeviceClass changes the crush device class of an OSD. Pools whose crush rule targets the old
+// or the new class will move data to or from the OSD.
+func SetDeviceClass(context *clusterd.Context, clusterName string, osdID int, class string) error {
+	if class == "" {
+		return fmt.Errorf("device class for osd.%d must not be empty", osdID)
+	}
+
+	// ceph refuses to change the class of an osd that already has one, so the old class is removed first
+	osd := fmt.Sprintf("osd.%d", osdID)
+	args := []string{"osd", "crush", "rm-device-class", osd}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return fmt.Errorf("failed to remove device class of %s: %+v, %s", osd, err, string(buf))
+	}
+
+	args = []string{"osd", "crush", "set-device-class", class, osd}
+	buf, err = NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return fmt.Errorf("failed to set device class of %s to %s: %+v, %s", osd, class, err, string(buf))
+	}
+
+	logger.Infof("set device class of %s to %s", osd, class)
+	return nil
+}
+
 func FindOSDInCrushMap(context *clusterd.Context, clusterName string, osdID int) (*CrushFindResult, error) {
 	args := []string{"osd", "find", strconv.Itoa(osdID)}
 	buf, err := NewCephCommand(context, clusterName, args).Run()
@@ -273,3 +273,40 @@ func TestCrushLocation(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Contains(t, err.Error(), "is not in a valid format")
 }
+
+func TestDeviceClasses(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	classCommands := [][]string{}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		if args[0] != "osd" || args[1] != "crush" {
+			return "", fmt.Errorf("unexpected ceph command '%v'", args)
+		}
+		switch {
+		case args[2] == "class" && args[3] == "ls":
+			return `["hdd","ssd"]`, nil
+		case args[2] == "class" && args[3] == "ls-osd" && args[4] == "hdd":
+			return `[0,1,2]`, nil
+		case args[2] == "class" && args[3] == "ls-osd" && args[4] == "ssd":
+			return `[3]`, nil
+		case args[2] == "rm-device-class" || args[2] == "set-device-class":
+			classCommands = append(classCommands, args[2:5])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	classes, err := GetDeviceClasses(context, "rook")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]int{"hdd": {0, 1, 2}, "ssd": {3}}, classes)
+
+	err = SetDeviceClass(context, "rook", 2, "nvme")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(classCommands))
+	assert.Equal(t, []string{"rm-device-class", "osd.2"}, classCommands[0][:2])
+	assert.Equal(t, []string{"set-device-class", "nvme", "osd.2"}, classCommands[1])
+
+	err = SetDeviceClass(context, "rook", 2, "")
+	assert.NotNil(t, err)
+}
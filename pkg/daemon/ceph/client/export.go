@@ -0,0 +1,163 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// ExportImage writes the full contents of a block storage image to a file. If snapName is not empty,
+// the contents of that snapshot are exported instead of the current contents of the image, which
+// gives a consistent export of an image that is in use.
+func ExportImage(context *clusterd.Context, clusterName, name, poolName, snapName, path string) error {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return err
+	}
+	if snapName != "" {
+		if err := validateName("snapshot", snapName); err != nil {
+			return err
+		}
+	}
+	if path == "" {
+		return fmt.Errorf("a path is required to export image %s", name)
+	}
+
+	spec := getImageSpec(name, poolName)
+	if snapName != "" {
+		spec = getSnapSpec(name, poolName, snapName)
+	}
+	args := []string{"export", spec, path, "--no-progress"}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("%s not found", spec)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return fmt.Errorf("cannot export %s, file %s already exists", spec, path)
+		}
+		return fmt.Errorf("failed to export %s to %s: %+v. output: %s", spec, path, err, string(buf))
+	}
+
+	logger.Infof("exported %s to %s", spec, path)
+	return nil
+}
+
+// ExportImageDiff writes the changes of a block storage image between two snapshots to a file in the
+// rbd export-diff format, which can be applied to a copy of the image with 'rbd import-diff'. If
+// fromSnap is empty, all allocated extents up to toSnap are exported. If toSnap is empty, the
+// changes up to the current contents of the image are exported.
+func ExportImageDiff(context *clusterd.Context, clusterName, name, poolName, fromSnap, toSnap, path string) error {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return err
+	}
+	for _, snapName := range []string{fromSnap, toSnap} {
+		if snapName != "" {
+			if err := validateName("snapshot", snapName); err != nil {
+				return err
+			}
+		}
+	}
+	if path == "" {
+		return fmt.Errorf("a path is required to export the diff of image %s", name)
+	}
+
+	spec := getImageSpec(name, poolName)
+	if toSnap != "" {
+		spec = getSnapSpec(name, poolName, toSnap)
+	}
+	args := []string{"export-diff", spec, path, "--no-progress"}
+	if fromSnap != "" {
+		args = append(args, "--from-snap", fromSnap)
+	}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			if fromSnap != "" {
+				return fmt.Errorf("%s or snapshot %s not found", spec, fromSnap)
+			}
+			return fmt.Errorf("%s not found", spec)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return fmt.Errorf("cannot export the diff of %s, file %s already exists", spec, path)
+		}
+		return fmt.Errorf("failed to export the diff of %s to %s: %+v. output: %s", spec, path, err, string(buf))
+	}
+
+	logger.Infof("exported the diff of %s from snapshot %q to %s", spec, fromSnap, path)
+	return nil
+}
+
+// ImportImage creates a new block storage image from the contents of a file, as written by
+// ExportImage. The image is created with the size of the file. If expectedSize is not 0, the file
+// is not imported unless it has the expected size, and if the imported image still has a different
+// size, the image is deleted again and an error is returned.
+func ImportImage(context *clusterd.Context, clusterName, path, name, poolName string, expectedSize uint64) (*CephBlockImage, error) {
+	if path == "" {
+		return nil, fmt.Errorf("a path is required to import image %s", name)
+	}
+	if err := validateName("image", name); err != nil {
+		return nil, err
+	}
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
+
+	if expectedSize != 0 {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the size of %s to import. %+v", path, err)
+		}
+		if uint64(info.Size()) != expectedSize {
+			return nil, fmt.Errorf("cannot import %s with size %d, expected %d", path, info.Size(), expectedSize)
+		}
+	}
+
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"import", path, imageSpec, "--no-progress"}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return nil, fmt.Errorf("cannot import %s, image %s already exists in pool %s", path, name, poolName)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("cannot import %s, file or pool %s not found", path, poolName)
+		}
+		return nil, fmt.Errorf("failed to import %s to image %s: %+v. output: %s", path, imageSpec, err, string(buf))
+	}
+
+	image, err := GetImageInfo(context, clusterName, name, poolName)
+	if err != nil {
+		return nil, err
+	}
+	if expectedSize != 0 && image.Size != expectedSize {
+		sizeErr := fmt.Errorf("imported image %s has size %d, expected %d", imageSpec, image.Size, expectedSize)
+		if err := DeleteImage(context, clusterName, name, poolName); err != nil {
+			return nil, fmt.Errorf("%+v. failed to delete the imported image. %+v", sizeErr, err)
+		}
+		return nil, sizeErr
+	}
+
+	logger.Infof("imported %s to image %s", path, imageSpec)
+	return image, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	var exportArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "export":
+			exportArgs = args
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	err := ExportImage(context, "foocluster", "image1", "pool1", "", "/backup/image1.img")
+	assert.Nil(t, err)
+	assert.Equal(t, "pool1/image1", exportArgs[1])
+	assert.Equal(t, "/backup/image1.img", exportArgs[2])
+
+	err = ExportImage(context, "foocluster", "image1", "pool1", "snap1", "/backup/image1.img")
+	assert.Nil(t, err)
+	assert.Equal(t, "pool1/image1@snap1", exportArgs[1])
+
+	exportArgs = nil
+	err = ExportImage(context, "foocluster", "image1", "pool1", "", "")
+	assert.NotNil(t, err)
+	assert.Nil(t, exportArgs)
+}
+
+func TestImportImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	file, err := ioutil.TempFile("", "image1.img")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	assert.Nil(t, file.Truncate(sizeMB))
+	file.Close()
+	path := file.Name()
+
+	var importArgs []string
+	importedSize := sizeMB
+	deleteCalled := false
+	var deleteErr error
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "import":
+			importArgs = args
+			return "", nil
+		case command == "rbd" && args[0] == "info":
+			return fmt.Sprintf(`{"name":"image1","size":%d,"format":2}`, importedSize), nil
+		case command == "rbd" && args[0] == "rm":
+			deleteCalled = true
+			return "", deleteErr
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	image, err := ImportImage(context, "foocluster", path, "image1", "pool1", uint64(sizeMB))
+	assert.Nil(t, err)
+	assert.Equal(t, "image1", image.Name)
+	assert.Equal(t, path, importArgs[1])
+	assert.Equal(t, "pool1/image1", importArgs[2])
+	assert.False(t, deleteCalled)
+
+	// the file is not imported if it doesn't have the expected size
+	importArgs = nil
+	image, err = ImportImage(context, "foocluster", path, "image1", "pool1", uint64(2*sizeMB))
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.Nil(t, importArgs)
+	assert.False(t, deleteCalled)
+
+	// without an expected size the file is imported as it is
+	image, err = ImportImage(context, "foocluster", "/backup/image1.img", "image1", "pool1", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "/backup/image1.img", importArgs[1])
+
+	// the image is removed if it doesn't have the expected size after the import
+	importedSize = 2 * sizeMB
+	image, err = ImportImage(context, "foocluster", path, "image1", "pool1", uint64(sizeMB))
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.True(t, deleteCalled)
+
+	// both errors are returned if the image cannot be removed
+	deleteErr = fmt.Errorf("mocked delete error")
+	image, err = ImportImage(context, "foocluster", path, "image1", "pool1", uint64(sizeMB))
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.Contains(t, err.Error(), "has size 2097152, expected 1048576")
+	assert.Contains(t, err.Error(), "mocked delete error")
+}
+
+func TestExportImageDiff(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	var exportArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "export-diff":
+			exportArgs = args
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	err := ExportImageDiff(context, "foocluster", "image1", "pool1", "snap1", "snap2", "/backup/image1.diff")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"export-diff", "pool1/image1@snap2", "/backup/image1.diff", "--no-progress", "--from-snap", "snap1"}, exportArgs[:6])
+
+	// without a starting snapshot, everything up to the snapshot is exported
+	err = ExportImageDiff(context, "foocluster", "image1", "pool1", "", "snap2", "/backup/image1.diff")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"export-diff", "pool1/image1@snap2", "/backup/image1.diff", "--no-progress"}, exportArgs[:4])
+	assert.NotContains(t, exportArgs, "--from-snap")
+}
@@ -322,8 +322,8 @@ func FailFilesystem(context *clusterd.Context, clusterName, fsName string) error
 }
 
 // RemoveFilesystem performs software configuration steps to remove a Ceph filesystem and its
-// backing pools.
-func RemoveFilesystem(context *clusterd.Context, clusterName, fsName string) error {
+// backing pools. If preservePools is set, the pools and their data are kept.
+func RemoveFilesystem(context *clusterd.Context, clusterName, fsName string, preservePools bool) error {
 	fs, err := GetFilesystem(context, clusterName, fsName)
 	if err != nil {
 		return fmt.Errorf("filesystem %s not found. %+v", fsName, err)
@@ -335,6 +335,11 @@ func RemoveFilesystem(context *clusterd.Context, clusterName, fsName string) err
 		return fmt.Errorf("Failed to delete ceph fs %s. err=%+v", fsName, err)
 	}
 
+	if preservePools {
+		logger.Infof("filesystem %s removed, preserving its pools", fsName)
+		return nil
+	}
+
 	err = deleteFSPools(context, clusterName, fs)
 	if err != nil {
 		return fmt.Errorf("failed to delete fs %s pools. %+v", fsName, err)
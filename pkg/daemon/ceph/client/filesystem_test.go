@@ -149,7 +149,13 @@ func TestFilesystemRemove(t *testing.T) {
 		}
 		return "", fmt.Errorf("unexpected ceph command '%v'", args)
 	}
-	err := RemoveFilesystem(context, "ns", fs.MDSMap.FilesystemName)
+	err := RemoveFilesystem(context, "ns", fs.MDSMap.FilesystemName, true)
+	assert.Nil(t, err)
+	assert.False(t, metadataDeleted)
+	assert.False(t, dataDeleted)
+	assert.False(t, crushDeleted)
+
+	err = RemoveFilesystem(context, "ns", fs.MDSMap.FilesystemName, false)
 	assert.Nil(t, err)
 	assert.True(t, metadataDeleted)
 	assert.True(t, dataDeleted)
@@ -0,0 +1,176 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// CephGroupImage is a member of an image group, as reported by 'rbd group image list'
+type CephGroupImage struct {
+	Image string `json:"image"`
+	Pool  string `json:"pool"`
+	State string `json:"state"`
+}
+
+// CreateImageGroup creates a group of block storage images. The images of a group can be
+// snapshotted together with crash consistency. Image groups are only available since mimic.
+func CreateImageGroup(context *clusterd.Context, clusterName string, cephVersion cephver.CephVersion, groupName, poolName string) error {
+	if !cephVersion.IsAtLeastMimic() {
+		return fmt.Errorf("image groups are not supported on ceph version %s", cephVersion.String())
+	}
+	if err := validateName("group", groupName); err != nil {
+		return err
+	}
+	if err := validateName("pool", poolName); err != nil {
+		return err
+	}
+
+	groupSpec := getImageSpec(groupName, poolName)
+	args := []string{"group", "create", groupSpec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return fmt.Errorf("image group %s already exists", groupSpec)
+		}
+		return fmt.Errorf("failed to create image group %s: %+v. output: %s", groupSpec, err, string(buf))
+	}
+
+	logger.Infof("created image group %s", groupSpec)
+	return nil
+}
+
+// AddImageToGroup adds a block storage image to an image group. An image can only be a member of
+// one group.
+func AddImageToGroup(context *clusterd.Context, clusterName, groupName, groupPoolName, imageName, imagePoolName string) error {
+	if err := validateName("group", groupName); err != nil {
+		return err
+	}
+	if err := validateName("group pool", groupPoolName); err != nil {
+		return err
+	}
+	if err := validateImageSpec(imageName, imagePoolName); err != nil {
+		return err
+	}
+	groupSpec := getImageSpec(groupName, groupPoolName)
+	imageSpec := getImageSpec(imageName, imagePoolName)
+	args := []string{"group", "image", "add", groupSpec, imageSpec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("image group %s or image %s not found", groupSpec, imageSpec)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return fmt.Errorf("image %s is already a member of a group", imageSpec)
+		}
+		return fmt.Errorf("failed to add image %s to group %s: %+v. output: %s", imageSpec, groupSpec, err, string(buf))
+	}
+
+	return nil
+}
+
+// RemoveImageFromGroup removes a block storage image from an image group.
+func RemoveImageFromGroup(context *clusterd.Context, clusterName, groupName, groupPoolName, imageName, imagePoolName string) error {
+	if err := validateName("group", groupName); err != nil {
+		return err
+	}
+	if err := validateName("group pool", groupPoolName); err != nil {
+		return err
+	}
+	if err := validateImageSpec(imageName, imagePoolName); err != nil {
+		return err
+	}
+	groupSpec := getImageSpec(groupName, groupPoolName)
+	imageSpec := getImageSpec(imageName, imagePoolName)
+	args := []string{"group", "image", "rm", groupSpec, imageSpec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("image %s is not a member of group %s", imageSpec, groupSpec)
+		}
+		return fmt.Errorf("failed to remove image %s from group %s: %+v. output: %s", imageSpec, groupSpec, err, string(buf))
+	}
+
+	return nil
+}
+
+// ListGroupImages lists the members of an image group.
+func ListGroupImages(context *clusterd.Context, clusterName, groupName, poolName string) ([]CephGroupImage, error) {
+	if err := validateName("group", groupName); err != nil {
+		return nil, err
+	}
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
+	groupSpec := getImageSpec(groupName, poolName)
+	args := []string{"group", "image", "list", groupSpec}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image group %s not found", groupSpec)
+		}
+		return nil, fmt.Errorf("failed to list images of group %s: %+v. output: %s", groupSpec, err, string(buf))
+	}
+
+	var images []CephGroupImage
+	if err := json.Unmarshal(buf, &images); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+	return images, nil
+}
+
+// CreateGroupSnapshot snapshots all of the images of a group at the same point in time. The members
+// of the group that were snapshotted are returned.
+func CreateGroupSnapshot(context *clusterd.Context, clusterName, groupName, poolName, snapName string) ([]CephGroupImage, error) {
+	if err := validateName("group", groupName); err != nil {
+		return nil, err
+	}
+	if err := validateName("snapshot", snapName); err != nil {
+		return nil, err
+	}
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
+
+	snapSpec := getSnapSpec(groupName, poolName, snapName)
+	args := []string{"group", "snap", "create", snapSpec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return nil, fmt.Errorf("group snapshot %s already exists", snapSpec)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image group %s not found in pool %s", groupName, poolName)
+		}
+		return nil, fmt.Errorf("failed to create group snapshot %s: %+v. output: %s", snapSpec, err, string(buf))
+	}
+
+	logger.Infof("created group snapshot %s", snapSpec)
+	return ListGroupImages(context, clusterName, groupName, poolName)
+}
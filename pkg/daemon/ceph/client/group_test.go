@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageGroup(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	var groupArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		if command != "rbd" || args[0] != "group" {
+			return "", fmt.Errorf("unexpected ceph command '%v'", args)
+		}
+		groupArgs = args
+		if args[1] == "image" && args[2] == "list" {
+			return `[{"image":"data","pool":"pool1","namespace":"","state":"attached"},` +
+				`{"image":"wal","pool":"pool2","namespace":"","state":"attached"}]`, nil
+		}
+		return "", nil
+	}
+
+	err := CreateImageGroup(context, "foocluster", cephver.Nautilus, "db", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"group", "create", "pool1/db"}, groupArgs[:3])
+
+	err = CreateImageGroup(context, "foocluster", cephver.Luminous, "db", "pool1")
+	assert.NotNil(t, err)
+
+	err = AddImageToGroup(context, "foocluster", "db", "pool1", "wal", "pool2")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"group", "image", "add", "pool1/db", "pool2/wal"}, groupArgs[:5])
+
+	err = RemoveImageFromGroup(context, "foocluster", "db", "pool1", "wal", "pool2")
+	assert.Nil(t, err)
+	assert.Equal(t, "rm", groupArgs[2])
+
+	images, err := CreateGroupSnapshot(context, "foocluster", "db", "pool1", "backup1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(images))
+	assert.Equal(t, CephGroupImage{Image: "wal", Pool: "pool2", State: "attached"}, images[1])
+
+	_, err = CreateGroupSnapshot(context, "foocluster", "db", "pool1", "")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// ListImageMeta returns the metadata key/value pairs set on a block storage image.
+func ListImageMeta(context *clusterd.Context, clusterName, name, poolName string) (map[string]string, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"image-meta", "list", getImageSpec(name, poolName)}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		return nil, fmt.Errorf("failed to list metadata of image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	meta := map[string]string{}
+	if strings.TrimSpace(string(buf)) == "" {
+		// rbd prints nothing when the image has no metadata
+		return meta, nil
+	}
+	if err := json.Unmarshal(buf, &meta); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	return meta, nil
+}
+
+// GetImageMeta returns the value of a single metadata key of a block storage image.
+func GetImageMeta(context *clusterd.Context, clusterName, name, poolName, key string) (string, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return "", err
+	}
+	args := []string{"image-meta", "get", getImageSpec(name, poolName), key}
+	buf, err := NewRBDCommand(context, clusterName, args).RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return "", fmt.Errorf("metadata key %s not found on image %s in pool %s", key, name, poolName)
+		}
+		return "", fmt.Errorf("failed to get metadata key %s of image %s in pool %s: %+v. output: %s", key, name, poolName, err, string(buf))
+	}
+
+	return strings.TrimSuffix(string(buf), "\n"), nil
+}
+
+// SetImageMeta sets a metadata key on a block storage image, replacing any previous value.
+func SetImageMeta(context *clusterd.Context, clusterName, name, poolName, key, value string) error {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("metadata key for image %s in pool %s must not be empty", name, poolName)
+	}
+
+	args := []string{"image-meta", "set", getImageSpec(name, poolName), key, value}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		return fmt.Errorf("failed to set metadata key %s of image %s in pool %s: %+v. output: %s", key, name, poolName, err, string(buf))
+	}
+
+	return nil
+}
+
+// RemoveImageMeta removes a metadata key from a block storage image.
+func RemoveImageMeta(context *clusterd.Context, clusterName, name, poolName, key string) error {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return err
+	}
+	args := []string{"image-meta", "remove", getImageSpec(name, poolName), key}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("metadata key %s not found on image %s in pool %s", key, name, poolName)
+		}
+		return fmt.Errorf("failed to remove metadata key %s of image %s in pool %s: %+v. output: %s", key, name, poolName, err, string(buf))
+	}
+
+	return nil
+}
+
+// CephImageQoS is the rate limits applied to the I/O of a block storage image. A limit of 0 means
+// the image is not limited by this client and inherits the pool or cluster configuration.
+type CephImageQoS struct {
+	IOPSLimit      uint64 `json:"iopsLimit"`
+	ReadIOPSLimit  uint64 `json:"readIopsLimit"`
+	WriteIOPSLimit uint64 `json:"writeIopsLimit"`
+	BPSLimit       uint64 `json:"bpsLimit"`
+	ReadBPSLimit   uint64 `json:"readBpsLimit"`
+	WriteBPSLimit  uint64 `json:"writeBpsLimit"`
+}
+
+// the image metadata keys that override the librbd qos settings for a single image
+const (
+	imageQoSIOPSLimitKey      = "conf_rbd_qos_iops_limit"
+	imageQoSReadIOPSLimitKey  = "conf_rbd_qos_read_iops_limit"
+	imageQoSWriteIOPSLimitKey = "conf_rbd_qos_write_iops_limit"
+	imageQoSBPSLimitKey       = "conf_rbd_qos_bps_limit"
+	imageQoSReadBPSLimitKey   = "conf_rbd_qos_read_bps_limit"
+	imageQoSWriteBPSLimitKey  = "conf_rbd_qos_write_bps_limit"
+)
+
+func (q *CephImageQoS) limits() map[string]*uint64 {
+	return map[string]*uint64{
+		imageQoSIOPSLimitKey:      &q.IOPSLimit,
+		imageQoSReadIOPSLimitKey:  &q.ReadIOPSLimit,
+		imageQoSWriteIOPSLimitKey: &q.WriteIOPSLimit,
+		imageQoSBPSLimitKey:       &q.BPSLimit,
+		imageQoSReadBPSLimitKey:   &q.ReadBPSLimit,
+		imageQoSWriteBPSLimitKey:  &q.WriteBPSLimit,
+	}
+}
+
+// GetImageQoS returns the qos limits set in the metadata of a block storage image.
+func GetImageQoS(context *clusterd.Context, clusterName, name, poolName string) (*CephImageQoS, error) {
+	meta, err := ListImageMeta(context, clusterName, name, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	qos := &CephImageQoS{}
+	for key, limit := range qos.limits() {
+		value, ok := meta[key]
+		if !ok {
+			continue
+		}
+		*limit, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %s for %s on image %s in pool %s. %+v", value, key, name, poolName, err)
+		}
+	}
+	return qos, nil
+}
+
+// SetImageQoS sets the qos limits of a block storage image and returns the resulting limits. Limits
+// that are 0 are removed from the image so that the pool or cluster configuration applies. The qos
+// throttle is only available in librbd since mimic, and it only applies to librbd clients; krbd
+// mappings are not limited.
+func SetImageQoS(context *clusterd.Context, clusterName string, cephVersion cephver.CephVersion, name, poolName string, qos CephImageQoS) (*CephImageQoS, error) {
+	if !cephVersion.IsAtLeastMimic() {
+		return nil, fmt.Errorf("image qos is not supported on ceph version %s", cephVersion.String())
+	}
+
+	meta, err := ListImageMeta(context, clusterName, name, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, limit := range qos.limits() {
+		if *limit > 0 {
+			if err := SetImageMeta(context, clusterName, name, poolName, key, strconv.FormatUint(*limit, 10)); err != nil {
+				return nil, err
+			}
+		} else if _, ok := meta[key]; ok {
+			if err := RemoveImageMeta(context, clusterName, name, poolName, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return GetImageQoS(context, clusterName, name, poolName)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageMeta(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	meta := map[string]string{}
+	var metaArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		if command != "rbd" || args[0] != "image-meta" {
+			return "", fmt.Errorf("unexpected ceph command '%v'", args)
+		}
+		metaArgs = args
+		switch args[1] {
+		case "list":
+			if len(meta) == 0 {
+				return "", nil
+			}
+			return `{"conf_rbd_qos_iops_limit":"1000","owner":"team-a"}`, nil
+		case "get":
+			return meta[args[3]] + "\n", nil
+		case "set":
+			meta[args[3]] = args[4]
+			return "", nil
+		case "remove":
+			delete(meta, args[3])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	result, err := ListImageMeta(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(result))
+	assert.Equal(t, []string{"image-meta", "list", "pool1/image1"}, metaArgs[:3])
+
+	err = SetImageMeta(context, "foocluster", "image1", "pool1", "owner", "team-a")
+	assert.Nil(t, err)
+	assert.Equal(t, "team-a", meta["owner"])
+	assert.Equal(t, []string{"image-meta", "set", "pool1/image1", "owner", "team-a"}, metaArgs[:5])
+	metaArgs = nil
+	err = SetImageMeta(context, "foocluster", "image1", "pool1", "", "team-a")
+	assert.NotNil(t, err)
+	assert.Nil(t, metaArgs)
+
+	value, err := GetImageMeta(context, "foocluster", "image1", "pool1", "owner")
+	assert.Nil(t, err)
+	assert.Equal(t, "team-a", value)
+	assert.Equal(t, []string{"image-meta", "get", "pool1/image1", "owner"}, metaArgs[:4])
+
+	result, err = ListImageMeta(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"conf_rbd_qos_iops_limit": "1000", "owner": "team-a"}, result)
+
+	err = RemoveImageMeta(context, "foocluster", "image1", "pool1", "owner")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(meta))
+	assert.Equal(t, []string{"image-meta", "remove", "pool1/image1", "owner"}, metaArgs[:4])
+}
+
+func TestSetImageQoS(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	meta := map[string]string{"conf_rbd_qos_bps_limit": "1048576", "owner": "team-a"}
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		if command != "rbd" || args[0] != "image-meta" {
+			return "", fmt.Errorf("unexpected ceph command '%v'", args)
+		}
+		switch args[1] {
+		case "list":
+			buf, _ := json.Marshal(meta)
+			return string(buf), nil
+		case "set":
+			meta[args[3]] = args[4]
+			return "", nil
+		case "remove":
+			delete(meta, args[3])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	qos, err := SetImageQoS(context, "foocluster", cephver.Nautilus, "image1", "pool1", CephImageQoS{IOPSLimit: 500, WriteBPSLimit: 2097152})
+	assert.Nil(t, err)
+	assert.Equal(t, CephImageQoS{IOPSLimit: 500, WriteBPSLimit: 2097152}, *qos)
+	assert.Equal(t, map[string]string{"conf_rbd_qos_iops_limit": "500", "conf_rbd_qos_write_bps_limit": "2097152", "owner": "team-a"}, meta)
+
+	_, err = SetImageQoS(context, "foocluster", cephver.Luminous, "image1", "pool1", CephImageQoS{IOPSLimit: 500})
+	assert.NotNil(t, err)
+
+	meta["conf_rbd_qos_read_iops_limit"] = "lots"
+	_, err = GetImageQoS(context, "foocluster", "image1", "pool1")
+	assert.NotNil(t, err)
+}
@@ -18,7 +18,10 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"syscall"
+	"time"
+	"unicode"
 
 	"strconv"
 
@@ -30,20 +33,117 @@ import (
 
 const (
 	ImageMinSize = uint64(1048576) // 1 MB
+
+	// ImageMinOrder and ImageMaxOrder are the bounds for the object size order of an image, where the
+	// size of the objects backing the image is 2^order bytes (4 KB to 32 MB).
+	ImageMinOrder = 12
+	ImageMaxOrder = 25
+	// ImageDefaultOrder is the order used by rbd when none is given (4 MB objects)
+	ImageDefaultOrder = 22
+
+	// MaxNameLength is the longest image or pool name accepted by the client
+	MaxNameLength = 255
 )
 
+// the image features that can be changed after an image is created. The other features can only be
+// set when the image is created, except for deep-flatten which can still be disabled.
+var (
+	imageFeaturesEnableable = map[string]bool{
+		"exclusive-lock": true, "object-map": true, "fast-diff": true, "journaling": true,
+	}
+	imageFeaturesDisableable = map[string]bool{
+		"exclusive-lock": true, "object-map": true, "fast-diff": true, "journaling": true, "deep-flatten": true,
+	}
+)
+
+// imageFeatureDependencies maps each rbd image feature that can be selected to the features it requires.
+// For example, object-map cannot be enabled without exclusive-lock.
+var imageFeatureDependencies = map[string][]string{
+	"layering":       {},
+	"striping":       {},
+	"exclusive-lock": {},
+	"object-map":     {"exclusive-lock"},
+	"fast-diff":      {"object-map"},
+	"deep-flatten":   {},
+	"journaling":     {"exclusive-lock"},
+}
+
 type CephBlockImage struct {
-	Name     string `json:"image"`
-	Size     uint64 `json:"size"`
-	Format   int    `json:"format"`
-	InfoName string `json:"name"`
+	Name     string   `json:"image"`
+	Size     uint64   `json:"size"`
+	Format   int      `json:"format"`
+	InfoName string   `json:"name"`
+	Features []string `json:"features,omitempty"`
+	// ID is the internal id of the image, which is only reported by 'rbd info' for format 2 images.
+	// Luminous does not report it, so it is taken from the block name prefix instead.
+	ID              string `json:"id,omitempty"`
+	BlockNamePrefix string `json:"block_name_prefix,omitempty"`
+	// Order, ObjectSize and CreateTimestamp are only reported by 'rbd info'. Format 1 images
+	// have no creation timestamp.
+	Order           int    `json:"order,omitempty"`
+	ObjectSize      uint64 `json:"object_size,omitempty"`
+	CreateTimestamp string `json:"create_timestamp,omitempty"`
+	// Parent is the snapshot the image was cloned from. It is not set for images that are not
+	// clones or that have been flattened.
+	Parent *CephImageParent `json:"parent,omitempty"`
+	// Mirroring is the mirroring state of the image, as reported by 'rbd info'
+	Mirroring *CephImageMirroring `json:"mirroring,omitempty"`
+}
+
+func (image *CephBlockImage) hasFeature(feature string) bool {
+	for _, f := range image.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// CephImageParent is the pool, image and snapshot a cloned image was created from. Overlap is the
+// number of bytes the clone still shares with its parent and is only reported by 'rbd info'.
+type CephImageParent struct {
+	Pool     string `json:"pool"`
+	Image    string `json:"image"`
+	Snapshot string `json:"snapshot"`
+	Overlap  uint64 `json:"overlap,omitempty"`
 }
 
-func ListImages(context *clusterd.Context, clusterName, poolName string) ([]CephBlockImage, error) {
+// CephImageWatcher is a client watching an image, as reported by 'rbd status'. An image is typically
+// watched by every client that has it mapped.
+type CephImageWatcher struct {
+	Address string `json:"address"`
+	Client  int    `json:"client"`
+	Cookie  uint64 `json:"cookie"`
+}
+
+// CephImageUsage is the provisioned and actually used space of an image, as reported by 'rbd du'
+type CephImageUsage struct {
+	Name string `json:"name"`
+	// Snapshot is set on the entries 'rbd du' reports for the snapshots of the image
+	Snapshot        string `json:"snapshot,omitempty"`
+	ProvisionedSize uint64 `json:"provisioned_size"`
+	UsedSize        uint64 `json:"used_size"`
+	// FastDiff is whether the usage was computed with the fast-diff feature. Without it, rbd
+	// has to scan all of the objects of the image, which is slow for large images.
+	FastDiff bool `json:"-"`
+}
+
+// ListImages lists the images in a pool. If namespace is not empty, the images in that rbd namespace
+// of the pool are listed instead. The images in namespaces are not included in the listing of the pool.
+func ListImages(context *clusterd.Context, clusterName, poolName, namespace string) ([]CephBlockImage, error) {
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
 	args := []string{"ls", "-l", poolName}
+	if namespace != "" {
+		if err := validateName("namespace", namespace); err != nil {
+			return nil, err
+		}
+		args = append(args, "--namespace", namespace)
+	}
 	cmd := NewRBDCommand(context, clusterName, args)
 	cmd.JsonOutput = true
-	buf, err := cmd.Run()
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list images for pool %s: %+v", poolName, err)
 	}
@@ -66,9 +166,239 @@ func ListImages(context *clusterd.Context, clusterName, poolName string) ([]Ceph
 	return images, nil
 }
 
+// GetImageInfo gets the details of a single block storage image without listing the rest of the pool.
+func GetImageInfo(context *clusterd.Context, clusterName, name, poolName string) (*CephBlockImage, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"info", getImageSpec(name, poolName)}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		return nil, fmt.Errorf("failed to get info for image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	var image CephBlockImage
+	if err = json.Unmarshal(buf, &image); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	// 'rbd info' reports the image name in the "name" field rather than the "image" field used by 'rbd ls'
+	image.Name = image.InfoName
+	// the data objects of a format 2 image are named rbd_data.[<data pool id>.]<image id>
+	if image.ID == "" && image.Format == 2 && strings.HasPrefix(image.BlockNamePrefix, "rbd_data.") {
+		image.ID = image.BlockNamePrefix[strings.LastIndex(image.BlockNamePrefix, ".")+1:]
+	}
+	return &image, nil
+}
+
+// GetImageUsage gets the provisioned size and the space actually used by a thin-provisioned image.
+func GetImageUsage(context *clusterd.Context, clusterName, name, poolName string) (*CephImageUsage, error) {
+	image, err := GetImageInfo(context, clusterName, name, poolName)
+	if err != nil {
+		return nil, err
+	}
+	fastDiff := image.hasFeature("fast-diff")
+	if !fastDiff {
+		logger.Infof("image %s in pool %s does not have the fast-diff feature, its usage requires a full scan", name, poolName)
+	}
+
+	args := []string{"du", getImageSpec(name, poolName)}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage of image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	var du struct {
+		Images []CephImageUsage `json:"images"`
+	}
+	if err = json.Unmarshal(buf, &du); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+	for i := range du.Images {
+		// the snapshots of the image are listed with the same name before the image itself
+		if du.Images[i].Name == name && du.Images[i].Snapshot == "" {
+			du.Images[i].FastDiff = fastDiff
+			return &du.Images[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("usage of image %s not found in pool %s", name, poolName)
+}
+
+// GetImageWatchers lists the clients that are currently watching an image.
+func GetImageWatchers(context *clusterd.Context, clusterName, name, poolName string) ([]CephImageWatcher, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"status", getImageSpec(name, poolName)}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	var status struct {
+		Watchers []CephImageWatcher `json:"watchers"`
+	}
+	if err = json.Unmarshal(buf, &status); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	return status.Watchers, nil
+}
+
+// RebuildObjectMap rebuilds the object map of an image, for example after it was flagged as invalid.
+// An invalid object map disables fast-diff, so 'rbd du' and diff exports have to scan every object.
+// Rebuilding reads all of the objects of the image, so it takes a long time for large images. The
+// time the rebuild took is returned.
+func RebuildObjectMap(context *clusterd.Context, clusterName, name, poolName string) (time.Duration, error) {
+	image, err := GetImageInfo(context, clusterName, name, poolName)
+	if err != nil {
+		return 0, err
+	}
+	if !image.hasFeature("object-map") {
+		return 0, fmt.Errorf("image %s in pool %s does not have the object-map feature", name, poolName)
+	}
+
+	start := time.Now()
+	args := []string{"object-map", "rebuild", getImageSpec(name, poolName), "--no-progress"}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild object map of image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	duration := time.Since(start)
+	logger.Infof("rebuilt object map of image %s in pool %s in %s", name, poolName, duration)
+	return duration, nil
+}
+
+// EnableImageFeatures enables features on an existing image. The features the new features depend on
+// must already be enabled or be enabled at the same time. The updated image is returned.
+func EnableImageFeatures(context *clusterd.Context, clusterName, name, poolName string, features []string) (*CephBlockImage, error) {
+	return updateImageFeatures(context, clusterName, name, poolName, features, true)
+}
+
+// DisableImageFeatures disables features on an existing image. Features that other enabled features
+// depend on can only be disabled together with them. The updated image is returned.
+func DisableImageFeatures(context *clusterd.Context, clusterName, name, poolName string, features []string) (*CephBlockImage, error) {
+	return updateImageFeatures(context, clusterName, name, poolName, features, false)
+}
+
+func updateImageFeatures(context *clusterd.Context, clusterName, name, poolName string, features []string, enable bool) (*CephBlockImage, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	action, allowed := "disable", imageFeaturesDisableable
+	if enable {
+		action, allowed = "enable", imageFeaturesEnableable
+	}
+	if len(features) == 0 {
+		return nil, fmt.Errorf("no features given to %s on image %s", action, name)
+	}
+
+	changed := map[string]bool{}
+	for _, feature := range features {
+		if _, ok := imageFeatureDependencies[feature]; !ok {
+			return nil, fmt.Errorf("unknown image feature %s", feature)
+		}
+		if !allowed[feature] {
+			return nil, fmt.Errorf("image feature %s cannot be %sd on an existing image", feature, action)
+		}
+		changed[feature] = true
+	}
+
+	image, err := GetImageInfo(context, clusterName, name, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	// check that the resulting set of features is still valid
+	result := []string{}
+	for _, feature := range image.Features {
+		if enable || !changed[feature] {
+			result = append(result, feature)
+		}
+	}
+	if enable {
+		for _, feature := range features {
+			if !image.hasFeature(feature) {
+				result = append(result, feature)
+			}
+		}
+	}
+	if err := validateImageFeatures(result); err != nil {
+		return nil, fmt.Errorf("cannot %s features %v on image %s. %+v", action, features, name, err)
+	}
+
+	args := append([]string{"feature", action, getImageSpec(name, poolName)}, features...)
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s features %v on image %s in pool %s: %+v. output: %s", action, features, name, poolName, err, string(buf))
+	}
+
+	logger.Infof("%sd features %v on image %s in pool %s", action, features, name, poolName)
+	return GetImageInfo(context, clusterName, name, poolName)
+}
+
+// BlacklistImageWatchers blacklists every client watching an image, so that an image left mapped by
+// a crashed node can be deleted. The blacklisted clients can no longer write to any image in the
+// cluster. Their watches are dropped once the watch timeout expires, so deleting the image may be
+// refused as busy until then. The watchers that were blacklisted are returned.
+func BlacklistImageWatchers(context *clusterd.Context, clusterName, name, poolName string, expire time.Duration) ([]CephImageWatcher, error) {
+	watchers, err := GetImageWatchers(context, clusterName, name, poolName)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, watcher := range watchers {
+		if err := BlacklistClient(context, clusterName, watcher.Address, expire); err != nil {
+			return nil, fmt.Errorf("failed to blacklist watcher of image %s in pool %s. %+v", name, poolName, err)
+		}
+	}
+	return watchers, nil
+}
+
 // CreateImage creates a block storage image.
+// If namespace is not empty, the image is created in that rbd namespace of the pool.
 // If dataPoolName is not empty, the image will use poolName as the metadata pool and the dataPoolname for data.
-func CreateImage(context *clusterd.Context, clusterName, name, poolName, dataPoolName string, size uint64) (*CephBlockImage, error) {
+// If order is 0, the image is created with the default object size order of 22 (4 MB objects).
+// If features is empty, the image is created with the default features of the rbd client.
+func CreateImage(context *clusterd.Context, clusterName, name, poolName, namespace, dataPoolName string, size uint64, order int, features []string) (*CephBlockImage, error) {
+	if err := validateName("image", name); err != nil {
+		return nil, err
+	}
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
+	if namespace != "" {
+		if err := validateName("namespace", namespace); err != nil {
+			return nil, err
+		}
+	}
+	if dataPoolName != "" {
+		if err := validateName("data pool", dataPoolName); err != nil {
+			return nil, err
+		}
+	}
+
+	if order != 0 && (order < ImageMinOrder || order > ImageMaxOrder) {
+		return nil, fmt.Errorf("invalid object size order %d for image %s, must be between %d and %d",
+			order, name, ImageMinOrder, ImageMaxOrder)
+	}
+
+	if err := validateImageFeatures(features); err != nil {
+		return nil, fmt.Errorf("invalid features for image %s. %+v", name, err)
+	}
+
 	if size > 0 && size < ImageMinSize {
 		// rbd tool uses MB as the smallest unit for size input.  0 is OK but anything else smaller
 		// than 1 MB should just be rounded up to 1 MB.
@@ -84,10 +414,22 @@ func CreateImage(context *clusterd.Context, clusterName, name, poolName, dataPoo
 
 	args := []string{"create", imageSpec, "--size", strconv.Itoa(sizeMB)}
 
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+
 	if dataPoolName != "" {
 		args = append(args, fmt.Sprintf("--data-pool=%s", dataPoolName))
 	}
 
+	if order != 0 {
+		args = append(args, "--order", strconv.Itoa(order))
+	}
+
+	for _, feature := range features {
+		args = append(args, "--image-feature", feature)
+	}
+
 	buf, err := NewRBDCommand(context, clusterName, args).Run()
 	if err != nil {
 		cmdErr, ok := err.(*exec.CommandError)
@@ -103,11 +445,179 @@ func CreateImage(context *clusterd.Context, clusterName, name, poolName, dataPoo
 	return &CephBlockImage{Name: name, Size: size}, nil
 }
 
+// CloneImage creates a copy-on-write clone of an image from one of its snapshots. The parent snapshot
+// must be protected before it can be cloned.
+func CloneImage(context *clusterd.Context, clusterName, parentName, parentPoolName, snapName, name, poolName string) (*CephBlockImage, error) {
+	if err := validateSnapSpec(parentName, parentPoolName, snapName); err != nil {
+		return nil, err
+	}
+	if err := validateName("image", name); err != nil {
+		return nil, err
+	}
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
+	snapshots, err := ListSnapshots(context, clusterName, parentName, parentPoolName)
+	if err != nil {
+		return nil, err
+	}
+
+	parentSpec := getSnapSpec(parentName, parentPoolName, snapName)
+	var parentSnap *CephSnapshot
+	for i := range snapshots {
+		if snapshots[i].Name == snapName {
+			parentSnap = &snapshots[i]
+			break
+		}
+	}
+	if parentSnap == nil {
+		return nil, fmt.Errorf("snapshot %s not found", parentSpec)
+	}
+	if !parentSnap.IsProtected() {
+		return nil, fmt.Errorf("snapshot %s must be protected before it can be cloned", parentSpec)
+	}
+
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"clone", parentSpec, imageSpec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return nil, fmt.Errorf("image %s already exists in pool %s", name, poolName)
+		}
+		return nil, fmt.Errorf("failed to clone snapshot %s to image %s: %+v. output: %s", parentSpec, imageSpec, err, string(buf))
+	}
+
+	return &CephBlockImage{Name: name, Size: parentSnap.Size}, nil
+}
+
+// FlattenImage copies all of the data from the parent snapshot into a cloned image so that the clone
+// no longer depends on its parent. The call blocks until all of the data is copied, which can take
+// minutes for large images.
+func FlattenImage(context *clusterd.Context, clusterName, name, poolName string) error {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return err
+	}
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"flatten", imageSpec, "--no-progress"}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EINVAL) {
+			return fmt.Errorf("image %s in pool %s is not a clone", name, poolName)
+		}
+		return fmt.Errorf("failed to flatten image %s: %+v. output: %s", imageSpec, err, string(buf))
+	}
+
+	logger.Infof("flattened image %s", imageSpec)
+	return nil
+}
+
+// ResizeImage grows or shrinks a block storage image to the given size.
+// Shrinking an image truncates its data, so it is refused unless allowShrink is set.
+func ResizeImage(context *clusterd.Context, clusterName, name, poolName string, size uint64, allowShrink bool) (*CephBlockImage, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, fmt.Errorf("cannot resize image %s in pool %s to a size of 0", name, poolName)
+	}
+
+	// round up to the next MB boundary in the same way as CreateImage
+	sizeMB := int((size + ImageMinSize - 1) / ImageMinSize)
+
+	if !allowShrink {
+		// check the current size rather than relying on rbd to refuse, since the data past the new size
+		// would be lost
+		image, err := GetImageInfo(context, clusterName, name, poolName)
+		if err != nil {
+			return nil, err
+		}
+		if uint64(sizeMB)*ImageMinSize < image.Size {
+			return nil, fmt.Errorf("cannot shrink image %s in pool %s from size %d to %d without allowShrink",
+				name, poolName, image.Size, uint64(sizeMB)*ImageMinSize)
+		}
+	}
+
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"resize", imageSpec, "--size", strconv.Itoa(sizeMB), "--no-progress"}
+	if allowShrink {
+		args = append(args, "--allow-shrink")
+	}
+
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EINVAL) && !allowShrink {
+			return nil, fmt.Errorf("failed to resize image %s in pool %s to size %d, shrinking requires allowShrink. output: %s",
+				name, poolName, size, string(buf))
+		}
+		return nil, fmt.Errorf("failed to resize image %s in pool %s to size %d: %+v. output: %s",
+			name, poolName, size, err, string(buf))
+	}
+
+	return &CephBlockImage{Name: name, Size: uint64(sizeMB) * ImageMinSize}, nil
+}
+
+// RenameImage renames a block storage image within its pool.
+func RenameImage(context *clusterd.Context, clusterName, name, newName, poolName string) (*CephBlockImage, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	if err := validateName("image", newName); err != nil {
+		return nil, fmt.Errorf("invalid new name for image %s in pool %s. %+v", name, poolName, err)
+	}
+	if newName == name {
+		return nil, fmt.Errorf("new name for image %s in pool %s must differ from the current name", name, poolName)
+	}
+
+	args := []string{"rename", getImageSpec(name, poolName), getImageSpec(newName, poolName)}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return nil, fmt.Errorf("cannot rename image %s, image %s already exists in pool %s", name, newName, poolName)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		return nil, fmt.Errorf("failed to rename image %s to %s in pool %s: %+v. output: %s",
+			name, newName, poolName, err, string(buf))
+	}
+
+	return &CephBlockImage{Name: newName}, nil
+}
+
 func DeleteImage(context *clusterd.Context, clusterName, name, poolName string) error {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return err
+	}
 	imageSpec := getImageSpec(name, poolName)
 	args := []string{"rm", imageSpec}
 	buf, err := NewRBDCommand(context, clusterName, args).Run()
 	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EBUSY) {
+			if watchers, watchErr := GetImageWatchers(context, clusterName, name, poolName); watchErr == nil && len(watchers) > 0 {
+				return fmt.Errorf("failed to delete image %s in pool %s, image is in use by %d clients: %+v",
+					name, poolName, len(watchers), watchers)
+			}
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOTEMPTY) {
+			if snapshots, snapErr := ListSnapshots(context, clusterName, name, poolName); snapErr == nil && len(snapshots) > 0 {
+				names := []string{}
+				for _, snap := range snapshots {
+					names = append(names, snap.Name)
+				}
+				return fmt.Errorf("failed to delete image %s in pool %s, the snapshots %v must be deleted first", name, poolName, names)
+			}
+		}
 		return fmt.Errorf("failed to delete image %s in pool %s: %+v. output: %s",
 			name, poolName, err, string(buf))
 	}
@@ -117,6 +627,9 @@ func DeleteImage(context *clusterd.Context, clusterName, name, poolName string)
 
 // MapImage maps an RBD image using admin cephfx and returns the device path
 func MapImage(context *clusterd.Context, imageName, poolName, id, keyring, clusterName, monitors string) error {
+	if err := validateImageSpec(imageName, poolName); err != nil {
+		return err
+	}
 	imageSpec := getImageSpec(imageName, poolName)
 	args := []string{
 		"map",
@@ -138,6 +651,9 @@ func MapImage(context *clusterd.Context, imageName, poolName, id, keyring, clust
 
 // UnMapImage unmap an RBD image from the node
 func UnMapImage(context *clusterd.Context, imageName, poolName, id, keyring, clusterName, monitors string, force bool) error {
+	if err := validateImageSpec(imageName, poolName); err != nil {
+		return err
+	}
 	deviceImage := getImageSpec(imageName, poolName)
 	args := []string{
 		"unmap",
@@ -161,6 +677,70 @@ func UnMapImage(context *clusterd.Context, imageName, poolName, id, keyring, clu
 	return nil
 }
 
+// validateImageFeatures checks that all the features are known to rbd and that the features each of
+// them depends on are also in the list.
+func validateImageFeatures(features []string) error {
+	selected := map[string]bool{}
+	for _, feature := range features {
+		if _, ok := imageFeatureDependencies[feature]; !ok {
+			return fmt.Errorf("unknown image feature %s", feature)
+		}
+		selected[feature] = true
+	}
+
+	for _, feature := range features {
+		for _, dependency := range imageFeatureDependencies[feature] {
+			if !selected[dependency] {
+				return fmt.Errorf("image feature %s requires feature %s", feature, dependency)
+			}
+		}
+	}
+	return nil
+}
+
+// validateName checks the name of a pool, image or snapshot before it is passed to the ceph tools. Names must not
+// contain the '/' and '@' separators of an image spec, must not be parsed as a command line option,
+// and must not contain surrounding whitespace or control characters.
+func validateName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s name must not be empty", kind)
+	}
+	if len(name) > MaxNameLength {
+		return fmt.Errorf("%s name %s is longer than %d characters", kind, name, MaxNameLength)
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("%s name %q must not have leading or trailing whitespace", kind, name)
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("%s name %s must not start with '-'", kind, name)
+	}
+	if strings.ContainsAny(name, "/@") {
+		return fmt.Errorf("%s name %s must not contain '/' or '@'", kind, name)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%s name %q must not contain control characters", kind, name)
+		}
+	}
+	return nil
+}
+
+// validateImageSpec checks the names of an image and its pool
+func validateImageSpec(name, poolName string) error {
+	if err := validateName("image", name); err != nil {
+		return err
+	}
+	return validateName("pool", poolName)
+}
+
+// validateSnapSpec checks the names of a snapshot and its image and pool
+func validateSnapSpec(imageName, poolName, snapName string) error {
+	if err := validateImageSpec(imageName, poolName); err != nil {
+		return err
+	}
+	return validateName("snapshot", snapName)
+}
+
 func getImageSpec(name, poolName string) string {
 	return fmt.Sprintf("%s/%s", poolName, name)
 }
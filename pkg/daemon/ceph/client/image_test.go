@@ -18,10 +18,12 @@ package client
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"strings"
 
 	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 )
@@ -43,7 +45,7 @@ func TestCreateImage(t *testing.T) {
 		}
 		return "", fmt.Errorf("unexpected ceph command '%v'", args)
 	}
-	image, err := CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB)) // 1MB
+	image, err := CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB), 0, nil) // 1MB
 	assert.NotNil(t, err)
 	assert.True(t, strings.Contains(err.Error(), "mocked detailed ceph error output stream"))
 
@@ -67,7 +69,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 0 byte --> 0 MB
 	expectedSizeArg = "0"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(0))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(0), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -75,7 +77,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 1 byte --> 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(1), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -83,7 +85,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (1 MB - 1 byte) --> 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB-1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB-1), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -91,7 +93,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 1 MB
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -101,7 +103,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (1 MB + 1 byte) --> 2 MB
 	expectedSizeArg = "2"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB+1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB+1), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -109,7 +111,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (2 MB - 1 byte) --> 2 MB
 	expectedSizeArg = "2"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2-1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB*2-1), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -117,7 +119,7 @@ func TestCreateImage(t *testing.T) {
 
 	// 2 MB
 	expectedSizeArg = "2"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB*2), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -125,7 +127,7 @@ func TestCreateImage(t *testing.T) {
 
 	// (2 MB + 1 byte) --> 3MB
 	expectedSizeArg = "3"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", uint64(sizeMB*2+1))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB*2+1), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
@@ -133,17 +135,92 @@ func TestCreateImage(t *testing.T) {
 
 	// Pool with data pool
 	expectedSizeArg = "1"
-	image, err = CreateImage(context, "foocluster", "image1", "pool1", "datapool1", uint64(sizeMB))
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "datapool1", uint64(sizeMB), 0, nil)
 	assert.Nil(t, err)
 	assert.NotNil(t, image)
 	assert.True(t, createCalled)
 	createCalled = false
 
+	// the object size order must be within the range allowed by rbd
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB), ImageMinOrder-1, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB), ImageMaxOrder+1, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.False(t, createCalled)
+
+	// a custom object size order is passed through to rbd
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "create":
+			createCalled = true
+			assert.Equal(t, []string{"--order", "20"}, args[4:6])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB), 20, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, image)
+	assert.True(t, createCalled)
+	createCalled = false
+
+	// unknown features and features with missing dependencies are rejected
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB), 0, []string{"layering", "bogus"})
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB), 0, []string{"layering", "object-map"})
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.True(t, strings.Contains(err.Error(), "requires feature exclusive-lock"))
+	assert.False(t, createCalled)
+
+	// the selected features are passed through to rbd
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "create":
+			createCalled = true
+			assert.Equal(t, []string{"--image-feature", "layering", "--image-feature", "exclusive-lock"}, args[4:8])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "", "", uint64(sizeMB), 0, []string{"layering", "exclusive-lock"})
+	assert.Nil(t, err)
+	assert.NotNil(t, image)
+	assert.True(t, createCalled)
+	createCalled = false
+
+	// the image is created in the namespace of the pool
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "create":
+			createCalled = true
+			assert.Equal(t, "pool1/image1", args[1])
+			assert.Equal(t, []string{"--namespace", "tenant-a"}, args[4:6])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "tenant-a", "", uint64(sizeMB), 0, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, image)
+	assert.True(t, createCalled)
+	createCalled = false
+
+	// an invalid namespace is rejected before calling rbd
+	image, err = CreateImage(context, "foocluster", "image1", "pool1", "tenant/a", "", uint64(sizeMB), 0, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.Contains(t, err.Error(), "namespace name")
+	assert.False(t, createCalled)
 }
 
 func TestListImageLogLevelInfo(t *testing.T) {
 	executor := &exectest.MockExecutor{}
 	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
 
 	var images []CephBlockImage
 	var err error
@@ -156,22 +233,25 @@ func TestListImageLogLevelInfo(t *testing.T) {
 			if emptyListResult {
 				return `[]`, nil
 			} else {
-				return `[{"image":"image1","size":1048576,"format":2},{"image":"image2","size":2048576,"format":2},{"image":"image3","size":3048576,"format":2}]`, nil
+				return `[{"image":"image1","size":1048576,"format":2},{"image":"image2","size":2048576,"format":2},{"image":"image3","size":3048576,"format":2,` +
+					`"parent":{"pool":"pool1","image":"image1","snapshot":"golden"}}]`, nil
 
 			}
 		}
 		return "", fmt.Errorf("unexpected ceph command '%v'", args)
 	}
 
-	images, err = ListImages(context, "foocluster", "pool1")
+	images, err = ListImages(context, "foocluster", "pool1", "")
 	assert.Nil(t, err)
 	assert.NotNil(t, images)
 	assert.True(t, len(images) == 3)
 	assert.True(t, listCalled)
+	assert.Nil(t, images[0].Parent)
+	assert.Equal(t, &CephImageParent{Pool: "pool1", Image: "image1", Snapshot: "golden"}, images[2].Parent)
 	listCalled = false
 
 	emptyListResult = true
-	images, err = ListImages(context, "foocluster", "pool1")
+	images, err = ListImages(context, "foocluster", "pool1", "")
 	assert.Nil(t, err)
 	assert.NotNil(t, images)
 	assert.True(t, len(images) == 0)
@@ -182,6 +262,7 @@ func TestListImageLogLevelInfo(t *testing.T) {
 func TestListImageLogLevelDebug(t *testing.T) {
 	executor := &exectest.MockExecutor{}
 	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
 
 	var images []CephBlockImage
 	var err error
@@ -226,7 +307,7 @@ func TestListImageLogLevelDebug(t *testing.T) {
 		return "", fmt.Errorf("unexpected ceph command '%v'", args)
 	}
 
-	images, err = ListImages(context, "foocluster", "pool1")
+	images, err = ListImages(context, "foocluster", "pool1", "")
 	assert.Nil(t, err)
 	assert.NotNil(t, images)
 	assert.True(t, len(images) == 3)
@@ -234,10 +315,457 @@ func TestListImageLogLevelDebug(t *testing.T) {
 	listCalled = false
 
 	emptyListResult = true
-	images, err = ListImages(context, "foocluster", "pool1")
+	images, err = ListImages(context, "foocluster", "pool1", "")
 	assert.Nil(t, err)
 	assert.NotNil(t, images)
 	assert.True(t, len(images) == 0)
 	assert.True(t, listCalled)
 	listCalled = false
 }
+
+func TestResizeImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	resizeCalled := false
+	expectedArgs := []string{}
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "info":
+			assert.Equal(t, "pool1/image1", args[1])
+			return fmt.Sprintf(`{"name":"image1","size":%d,"objects":2,"order":20,"object_size":1048576,"format":2}`, sizeMB*2), nil
+		case command == "rbd" && args[0] == "resize":
+			resizeCalled = true
+			assert.Equal(t, expectedArgs, args[1:len(expectedArgs)+1])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// a size of 0 is rejected without calling rbd
+	image, err := ResizeImage(context, "foocluster", "image1", "pool1", uint64(0), false)
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.False(t, resizeCalled)
+
+	// growing an image rounds up to the next MB
+	expectedArgs = []string{"pool1/image1", "--size", "3", "--no-progress"}
+	image, err = ResizeImage(context, "foocluster", "image1", "pool1", uint64(sizeMB*2+1), false)
+	assert.Nil(t, err)
+	assert.True(t, resizeCalled)
+	assert.Equal(t, uint64(sizeMB*3), image.Size)
+	resizeCalled = false
+
+	// resizing to the current size is not a shrink
+	expectedArgs = []string{"pool1/image1", "--size", "2", "--no-progress"}
+	image, err = ResizeImage(context, "foocluster", "image1", "pool1", uint64(sizeMB*2), false)
+	assert.Nil(t, err)
+	assert.True(t, resizeCalled)
+	assert.Equal(t, uint64(sizeMB*2), image.Size)
+	resizeCalled = false
+
+	// shrinking below the current size is refused without allowShrink and rbd resize is never called
+	image, err = ResizeImage(context, "foocluster", "image1", "pool1", uint64(sizeMB), false)
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.Contains(t, err.Error(), "without allowShrink")
+	assert.False(t, resizeCalled)
+
+	// shrinking must pass the allow shrink flag through to rbd
+	expectedArgs = []string{"pool1/image1", "--size", "1", "--no-progress", "--allow-shrink"}
+	image, err = ResizeImage(context, "foocluster", "image1", "pool1", uint64(sizeMB), true)
+	assert.Nil(t, err)
+	assert.True(t, resizeCalled)
+	assert.Equal(t, "image1", image.Name)
+	assert.Equal(t, uint64(sizeMB), image.Size)
+
+	// errors from rbd are returned along with the output
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		return "mocked resize output", fmt.Errorf("some mocked error")
+	}
+	image, err = ResizeImage(context, "foocluster", "image1", "pool1", uint64(sizeMB), true)
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.True(t, strings.Contains(err.Error(), "mocked resize output"))
+}
+
+func TestRenameImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	renameCalled := false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "rename":
+			renameCalled = true
+			assert.Equal(t, "pool1/image1", args[1])
+			assert.Equal(t, "pool1/image2", args[2])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// the new name must be set and must be different
+	image, err := RenameImage(context, "foocluster", "image1", "", "pool1")
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	image, err = RenameImage(context, "foocluster", "image1", "image1", "pool1")
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.False(t, renameCalled)
+
+	image, err = RenameImage(context, "foocluster", "image1", "image2", "pool1")
+	assert.Nil(t, err)
+	assert.True(t, renameCalled)
+	assert.Equal(t, "image2", image.Name)
+}
+
+func TestGetImageInfo(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "info":
+			assert.Equal(t, "pool1/image1", args[1])
+			assert.Equal(t, "json", args[len(args)-1])
+			return `{"name":"image1","size":1048576,"objects":1,"order":20,"object_size":1048576,"block_name_prefix":"pool1_data.229226b8b4567",` +
+				`"format":2,"features":["layering"],"op_features":[],"flags":[],"create_timestamp":"Fri Oct  5 19:46:20 2018"}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	image, err := GetImageInfo(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, "image1", image.Name)
+	assert.Equal(t, uint64(sizeMB), image.Size)
+	assert.Equal(t, 2, image.Format)
+	assert.Equal(t, []string{"layering"}, image.Features)
+	assert.Equal(t, 20, image.Order)
+	assert.Equal(t, uint64(1048576), image.ObjectSize)
+	assert.Equal(t, "Fri Oct  5 19:46:20 2018", image.CreateTimestamp)
+	assert.Nil(t, image.Parent)
+
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		return "rbd: error opening image image1: (2) No such file or directory", fmt.Errorf("some mocked error")
+	}
+	image, err = GetImageInfo(context, "foocluster", "image1", "pool1")
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+}
+
+func TestCloneImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	cloneCalled := false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "snap" && args[1] == "ls":
+			return `[{"id":4,"name":"snap1","size":1048576,"protected":"false","timestamp":"Tue Jun 11 21:02:40 2019"},` +
+				`{"id":5,"name":"golden","size":2097152,"protected":"true","timestamp":"Tue Jun 11 21:03:10 2019"}]`, nil
+		case command == "rbd" && args[0] == "clone":
+			cloneCalled = true
+			assert.Equal(t, "pool1/image1@golden", args[1])
+			assert.Equal(t, "pool2/clone1", args[2])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// the snapshot must exist
+	image, err := CloneImage(context, "foocluster", "image1", "pool1", "missing", "clone1", "pool2")
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+
+	// the snapshot must be protected
+	image, err = CloneImage(context, "foocluster", "image1", "pool1", "snap1", "clone1", "pool2")
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.True(t, strings.Contains(err.Error(), "must be protected"))
+	assert.False(t, cloneCalled)
+
+	image, err = CloneImage(context, "foocluster", "image1", "pool1", "golden", "clone1", "pool2")
+	assert.Nil(t, err)
+	assert.True(t, cloneCalled)
+	assert.Equal(t, "clone1", image.Name)
+	assert.Equal(t, uint64(2097152), image.Size)
+}
+
+func TestGetImageUsage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	features := `"layering"`
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "info":
+			return `{"name":"image1","size":1073741824,"objects":256,"order":22,"object_size":4194304,"format":2,` +
+				`"features":[` + features + `],"flags":[]}`, nil
+		case command == "rbd" && args[0] == "du":
+			assert.Equal(t, "pool1/image1", args[1])
+			// the snapshots of the image are listed before the image itself
+			return `{"images":[{"name":"image1","snapshot":"snap1","provisioned_size":1073741824,"used_size":4194304},` +
+				`{"name":"image1","snapshot":"snap2","provisioned_size":1073741824,"used_size":6291456},` +
+				`{"name":"image1","provisioned_size":1073741824,"used_size":8388608}],` +
+				`"total_provisioned_size":1073741824,"total_used_size":18874368}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	usage, err := GetImageUsage(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1073741824), usage.ProvisionedSize)
+	assert.Equal(t, uint64(8388608), usage.UsedSize)
+	assert.Equal(t, "", usage.Snapshot)
+	assert.False(t, usage.FastDiff)
+
+	features = `"layering","exclusive-lock","object-map","fast-diff"`
+	usage, err = GetImageUsage(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.True(t, usage.FastDiff)
+}
+
+func TestValidateName(t *testing.T) {
+	assert.Nil(t, validateName("image", "pvc-0a1b2c3d"))
+	assert.Nil(t, validateName("image", "my_image.v2"))
+
+	assert.NotNil(t, validateName("image", ""))
+	assert.NotNil(t, validateName("image", " image1"))
+	assert.NotNil(t, validateName("image", "image1\n"))
+	assert.NotNil(t, validateName("image", "--pool=other"))
+	assert.NotNil(t, validateName("image", "pool1/image1"))
+	assert.NotNil(t, validateName("image", "image1@snap"))
+	assert.NotNil(t, validateName("image", "image\x001"))
+	assert.NotNil(t, validateName("image", strings.Repeat("a", MaxNameLength+1)))
+
+	// invalid names are rejected before running rbd
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	context := &clusterd.Context{Executor: executor}
+	_, err := CreateImage(context, "foocluster", "image1", "pool1/other", "", "", uint64(sizeMB), 0, nil)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "pool name")
+	_, err = RenameImage(context, "foocluster", "image1", "-image2", "pool1")
+	assert.NotNil(t, err)
+	_, err = CloneImage(context, "foocluster", "image1", "pool1", "snap1", "clone/1", "pool1")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "image name")
+	_, err = RestoreImage(context, "foocluster", "pool1", "1c4f6b8b4567", "image@2")
+	assert.NotNil(t, err)
+	_, err = CreateSnapshot(context, "foocluster", "image1", "pool1", "-snap1")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "snapshot name")
+
+	// the pool is validated by the other rbd wrappers as well
+	err = DeleteImage(context, "foocluster", "image1", "--pool=other")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "pool name")
+	_, err = ListSnapshots(context, "foocluster", "image1", "")
+	assert.NotNil(t, err)
+	_, err = ListTrash(context, "foocluster", " pool1")
+	assert.NotNil(t, err)
+	_, err = ListImageLocks(context, "foocluster", "image1", "pool1/other")
+	assert.NotNil(t, err)
+	err = SetImageMeta(context, "foocluster", "image1", "pool1@snap", "key", "value")
+	assert.NotNil(t, err)
+}
+
+func TestValidateNamesInWrappers(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		assert.Fail(t, "unexpected command", "%s %v", command, args)
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	mockRBDTimeout(executor)
+	context := &clusterd.Context{Executor: executor}
+
+	for _, bad := range []string{"-foo", "a@b", "a/b", ""} {
+		calls := map[string]func() error{
+			"GetImageInfo image": func() error { _, err := GetImageInfo(context, "foocluster", bad, "pool1"); return err },
+			"GetImageInfo pool":  func() error { _, err := GetImageInfo(context, "foocluster", "image1", bad); return err },
+			"GetImageUsage":      func() error { _, err := GetImageUsage(context, "foocluster", bad, "pool1"); return err },
+			"GetImageWatchers":   func() error { _, err := GetImageWatchers(context, "foocluster", bad, "pool1"); return err },
+			"EnableImageFeatures": func() error {
+				_, err := EnableImageFeatures(context, "foocluster", bad, "pool1", []string{"exclusive-lock"})
+				return err
+			},
+			"CloneImage parent": func() error {
+				_, err := CloneImage(context, "foocluster", bad, "pool1", "snap1", "clone1", "pool1")
+				return err
+			},
+			"CloneImage snapshot": func() error {
+				_, err := CloneImage(context, "foocluster", "image1", "pool1", bad, "clone1", "pool1")
+				return err
+			},
+			"FlattenImage":      func() error { return FlattenImage(context, "foocluster", bad, "pool1") },
+			"ResizeImage":       func() error { _, err := ResizeImage(context, "foocluster", bad, "pool1", sizeMB, false); return err },
+			"RenameImage":       func() error { _, err := RenameImage(context, "foocluster", bad, "image2", "pool1"); return err },
+			"DeleteImage":       func() error { return DeleteImage(context, "foocluster", bad, "pool1") },
+			"ListSnapshots":     func() error { _, err := ListSnapshots(context, "foocluster", bad, "pool1"); return err },
+			"DeleteSnapshot":    func() error { return DeleteSnapshot(context, "foocluster", "image1", "pool1", bad) },
+			"RollbackSnapshot":  func() error { return RollbackSnapshot(context, "foocluster", "image1", "pool1", bad) },
+			"ProtectSnapshot":   func() error { return ProtectSnapshot(context, "foocluster", bad, "pool1", "snap1") },
+			"UnprotectSnapshot": func() error { return UnprotectSnapshot(context, "foocluster", "image1", "pool1", bad) },
+			"ListSnapshotChildren": func() error {
+				_, err := ListSnapshotChildren(context, "foocluster", "image1", "pool1", bad)
+				return err
+			},
+			"ListImageLocks":  func() error { _, err := ListImageLocks(context, "foocluster", bad, "pool1"); return err },
+			"RemoveImageLock": func() error { return RemoveImageLock(context, "foocluster", bad, "pool1", "lock1", "client.4125") },
+			"ListImageMeta":   func() error { _, err := ListImageMeta(context, "foocluster", bad, "pool1"); return err },
+			"SetImageMeta":    func() error { return SetImageMeta(context, "foocluster", bad, "pool1", "key", "value") },
+			"RemoveImageMeta": func() error { return RemoveImageMeta(context, "foocluster", bad, "pool1", "key") },
+			"TrashImage": func() error {
+				_, err := TrashImage(context, "foocluster", cephver.Nautilus, bad, "pool1", 0)
+				return err
+			},
+			"ExportImage": func() error { return ExportImage(context, "foocluster", bad, "pool1", "", "/backup/image1.img") },
+			"ExportImage snap": func() error {
+				return ExportImage(context, "foocluster", "image1", "pool1", bad, "/backup/image1.img")
+			},
+			"ExportImageDiff": func() error {
+				return ExportImageDiff(context, "foocluster", "image1", "pool1", bad, "snap2", "/backup/image1.diff")
+			},
+			"AddImageToGroup group": func() error { return AddImageToGroup(context, "foocluster", bad, "pool1", "image1", "pool1") },
+			"AddImageToGroup image": func() error { return AddImageToGroup(context, "foocluster", "group1", "pool1", bad, "pool1") },
+			"RemoveImageFromGroup": func() error {
+				return RemoveImageFromGroup(context, "foocluster", "group1", "pool1", bad, "pool1")
+			},
+			"ListGroupImages": func() error { _, err := ListGroupImages(context, "foocluster", bad, "pool1"); return err },
+			"CreateGroupSnapshot": func() error {
+				_, err := CreateGroupSnapshot(context, "foocluster", "group1", "pool1", bad)
+				return err
+			},
+			"DisableImageMirroring": func() error { _, err := DisableImageMirroring(context, "foocluster", bad, "pool1", false); return err },
+			"PromoteImage":          func() error { _, err := PromoteImage(context, "foocluster", bad, "pool1", false); return err },
+			"DemoteImage":           func() error { _, err := DemoteImage(context, "foocluster", bad, "pool1"); return err },
+			"MapImage":              func() error { return MapImage(context, bad, "pool1", "admin", "", "foocluster", "") },
+		}
+		for name, call := range calls {
+			// an empty snapshot name means no snapshot for the export calls
+			if bad == "" && strings.HasPrefix(name, "Export") && name != "ExportImage" {
+				continue
+			}
+			assert.NotNil(t, call(), "%s accepted the invalid name %q", name, bad)
+		}
+	}
+}
+
+func TestBlacklistImageWatchers(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "status":
+			return `{"watchers":[{"address":"10.0.0.5:0/2774836903","client":4125,"cookie":140147718739456}]}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	blacklisted := []string{}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "osd" && args[1] == "blacklist" && args[2] == "add":
+			blacklisted = append(blacklisted, args[3])
+			assert.Equal(t, "3600", args[4])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	watchers, err := BlacklistImageWatchers(context, "foocluster", "image1", "pool1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(watchers))
+	assert.Equal(t, []string{"10.0.0.5:0/2774836903"}, blacklisted)
+}
+
+func TestRebuildObjectMap(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	features := `["layering","exclusive-lock","object-map","fast-diff"]`
+	rebuildCalled := false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "info":
+			return `{"name":"image1","size":1048576,"format":2,"features":` + features + `}`, nil
+		case command == "rbd" && args[0] == "object-map" && args[1] == "rebuild":
+			rebuildCalled = true
+			assert.Equal(t, "pool1/image1", args[2])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	_, err := RebuildObjectMap(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.True(t, rebuildCalled)
+
+	// the object map can only be rebuilt if the feature is enabled
+	rebuildCalled = false
+	features = `["layering"]`
+	_, err = RebuildObjectMap(context, "foocluster", "image1", "pool1")
+	assert.NotNil(t, err)
+	assert.False(t, rebuildCalled)
+}
+
+func TestUpdateImageFeatures(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	features := `["layering","exclusive-lock"]`
+	var featureArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "info":
+			return `{"name":"image1","size":1048576,"format":2,"features":` + features + `}`, nil
+		case command == "rbd" && args[0] == "feature":
+			featureArgs = args
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	_, err := EnableImageFeatures(context, "foocluster", "image1", "pool1", []string{"object-map", "fast-diff"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"feature", "enable", "pool1/image1", "object-map", "fast-diff"}, featureArgs[:5])
+
+	// fast-diff requires object-map
+	featureArgs = nil
+	_, err = EnableImageFeatures(context, "foocluster", "image1", "pool1", []string{"fast-diff"})
+	assert.NotNil(t, err)
+	// layering can only be set at creation
+	_, err = EnableImageFeatures(context, "foocluster", "image1", "pool1", []string{"layering"})
+	assert.NotNil(t, err)
+	_, err = EnableImageFeatures(context, "foocluster", "image1", "pool1", []string{"bogus"})
+	assert.NotNil(t, err)
+	assert.Nil(t, featureArgs)
+
+	// exclusive-lock cannot be disabled while object-map depends on it
+	features = `["layering","exclusive-lock","object-map"]`
+	_, err = DisableImageFeatures(context, "foocluster", "image1", "pool1", []string{"exclusive-lock"})
+	assert.NotNil(t, err)
+	assert.Nil(t, featureArgs)
+
+	_, err = DisableImageFeatures(context, "foocluster", "image1", "pool1", []string{"object-map", "exclusive-lock"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"feature", "disable", "pool1/image1", "object-map", "exclusive-lock"}, featureArgs[:5])
+}
+
+// mockRBDTimeout sends the rbd commands that are run with a timeout to the same mock as the
+// commands that are run without one
+func mockRBDTimeout(executor *exectest.MockExecutor) {
+	executor.MockExecuteCommandWithTimeout = func(debug bool, timeout time.Duration, actionName string, command string, args ...string) (string, error) {
+		return executor.MockExecuteCommandWithOutput(debug, actionName, command, args...)
+	}
+}
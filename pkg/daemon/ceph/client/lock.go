@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// CephImageLock is a lock held on a block storage image, as reported by 'rbd lock ls'. The exclusive
+// lock feature takes locks with an "auto" id on behalf of the client that has the image open.
+type CephImageLock struct {
+	ID      string `json:"id"`
+	Locker  string `json:"locker"`
+	Address string `json:"address"`
+}
+
+// ListImageLocks lists the locks held on a block storage image.
+func ListImageLocks(context *clusterd.Context, clusterName, name, poolName string) ([]CephImageLock, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"lock", "ls", getImageSpec(name, poolName)}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		return nil, fmt.Errorf("failed to list locks of image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	return parseImageLocks(buf)
+}
+
+// RemoveImageLock breaks a lock held on a block storage image, for example by a client on a node
+// that died with the image still mapped. Removing the lock does not stop the client from writing
+// to the image if it is still running; it should be blacklisted first.
+func RemoveImageLock(context *clusterd.Context, clusterName, name, poolName, lockID, locker string) error {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return err
+	}
+	if lockID == "" || locker == "" {
+		return fmt.Errorf("lock id and locker are required to remove a lock of image %s", name)
+	}
+
+	args := []string{"lock", "rm", getImageSpec(name, poolName), lockID, locker}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("lock %s held by %s not found on image %s in pool %s", lockID, locker, name, poolName)
+		}
+		return fmt.Errorf("failed to remove lock %s of image %s in pool %s: %+v. output: %s", lockID, name, poolName, err, string(buf))
+	}
+
+	logger.Infof("removed lock %s held by %s on image %s in pool %s", lockID, locker, name, poolName)
+	return nil
+}
+
+func parseImageLocks(buf []byte) ([]CephImageLock, error) {
+	if strings.TrimSpace(string(buf)) == "" {
+		return []CephImageLock{}, nil
+	}
+
+	// newer versions of rbd return a list of locks, older versions return a map keyed by the lock id
+	var locks []CephImageLock
+	if err := json.Unmarshal(buf, &locks); err == nil {
+		return locks, nil
+	}
+
+	var lockMap map[string]CephImageLock
+	if err := json.Unmarshal(buf, &lockMap); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+	locks = []CephImageLock{}
+	for id, lock := range lockMap {
+		lock.ID = id
+		locks = append(locks, lock)
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].ID < locks[j].ID })
+	return locks, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListImageLocks(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	output := `[{"id":"auto 140147718739456","locker":"client.4125","address":"10.0.0.5:0/2774836903"}]`
+	var listArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "lock" && args[1] == "ls":
+			listArgs = args
+			return output, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	locks, err := ListImageLocks(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"lock", "ls", "pool1/image1"}, listArgs[:3])
+	assert.Equal(t, []CephImageLock{{ID: "auto 140147718739456", Locker: "client.4125", Address: "10.0.0.5:0/2774836903"}}, locks)
+
+	// older versions of rbd key the locks by their id
+	output = `{"auto 2":{"locker":"client.2","address":"10.0.0.2:0/2"},"auto 1":{"locker":"client.1","address":"10.0.0.1:0/1"}}`
+	locks, err = ListImageLocks(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(locks))
+	assert.Equal(t, "auto 1", locks[0].ID)
+	assert.Equal(t, "client.1", locks[0].Locker)
+
+	output = ""
+	locks, err = ListImageLocks(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(locks))
+}
+
+func TestRemoveImageLock(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	removeCalled := false
+	var removeArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "lock" && args[1] == "rm":
+			removeCalled = true
+			removeArgs = args
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	err := RemoveImageLock(context, "foocluster", "image1", "pool1", "auto 1", "client.1")
+	assert.Nil(t, err)
+	assert.True(t, removeCalled)
+	assert.Equal(t, []string{"lock", "rm", "pool1/image1", "auto 1", "client.1"}, removeArgs[:5])
+
+	removeCalled = false
+	err = RemoveImageLock(context, "foocluster", "image1", "pool1", "", "client.1")
+	assert.NotNil(t, err)
+	assert.False(t, removeCalled)
+}
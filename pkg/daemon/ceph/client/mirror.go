@@ -0,0 +1,211 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+const (
+	// ImageMirrorModeJournal replicates every write through the image journal. It requires the
+	// journaling feature on the image.
+	ImageMirrorModeJournal = "journal"
+	// ImageMirrorModeSnapshot replicates the image by periodically mirroring snapshots. It is only
+	// available since octopus.
+	ImageMirrorModeSnapshot = "snapshot"
+)
+
+// CephImageMirroring is the mirroring state of an image, as reported by 'rbd info'
+type CephImageMirroring struct {
+	State    string `json:"state"`
+	GlobalID string `json:"global_id"`
+	Primary  bool   `json:"primary"`
+}
+
+// IsEnabled returns whether mirroring is enabled on the image
+func (m *CephImageMirroring) IsEnabled() bool {
+	return m.State == "enabled"
+}
+
+// EnableImageMirroring enables mirroring of a single image in the given mode. The pool the image
+// belongs to must already have mirroring enabled in image mode.
+func EnableImageMirroring(context *clusterd.Context, clusterName string, cephVersion cephver.CephVersion, name, poolName, mode string) (*CephImageMirroring, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"mirror", "image", "enable", getImageSpec(name, poolName)}
+	switch mode {
+	case "", ImageMirrorModeJournal:
+		// the mode can only be given to rbd since octopus, before that only journal mode exists
+		if mode != "" && cephVersion.IsAtLeastOctopus() {
+			args = append(args, mode)
+		}
+	case ImageMirrorModeSnapshot:
+		if !cephVersion.IsAtLeastOctopus() {
+			return nil, fmt.Errorf("snapshot based mirroring is not supported on ceph version %s", cephVersion.String())
+		}
+		args = append(args, mode)
+	default:
+		return nil, fmt.Errorf("unknown mirroring mode %s for image %s", mode, name)
+	}
+
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EINVAL) {
+			return nil, fmt.Errorf("cannot enable mirroring of image %s, pool %s must have mirroring enabled in image mode "+
+				"and journal based mirroring requires the journaling feature. output: %s", name, poolName, string(buf))
+		}
+		return nil, fmt.Errorf("failed to enable mirroring of image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	logger.Infof("enabled mirroring of image %s in pool %s", name, poolName)
+	return getImageMirroring(context, clusterName, name, poolName)
+}
+
+// DisableImageMirroring disables mirroring of a single image. A non-primary image can only be
+// disabled with force, which should only be used when the primary image is gone.
+func DisableImageMirroring(context *clusterd.Context, clusterName, name, poolName string, force bool) (*CephImageMirroring, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"mirror", "image", "disable", getImageSpec(name, poolName)}
+	if force {
+		args = append(args, "--force")
+	}
+
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		return nil, fmt.Errorf("failed to disable mirroring of image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	logger.Infof("disabled mirroring of image %s in pool %s", name, poolName)
+	return getImageMirroring(context, clusterName, name, poolName)
+}
+
+// PromoteImage makes a mirrored image the primary, so that it can be written to after a failover.
+// Promotion fails while the image is still primary in the peer cluster unless force is set. Forcing
+// the promotion when the peer is still in use results in a split brain, which has to be resolved by
+// demoting one of the images and resyncing it.
+func PromoteImage(context *clusterd.Context, clusterName, name, poolName string, force bool) (*CephImageMirroring, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"mirror", "image", "promote", getImageSpec(name, poolName)}
+	if force {
+		args = append(args, "--force")
+	}
+
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EBUSY) {
+			return nil, fmt.Errorf("cannot promote image %s in pool %s, it is still primary in the peer cluster. "+
+				"demote the peer image first or force the promotion. output: %s", name, poolName, string(buf))
+		}
+		return nil, fmt.Errorf("failed to promote image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	logger.Infof("promoted image %s in pool %s to primary", name, poolName)
+	return getImageMirroring(context, clusterName, name, poolName)
+}
+
+// DemoteImage makes a primary mirrored image non-primary so that the image in the peer cluster can be
+// promoted.
+func DemoteImage(context *clusterd.Context, clusterName, name, poolName string) (*CephImageMirroring, error) {
+	if err := validateImageSpec(name, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"mirror", "image", "demote", getImageSpec(name, poolName)}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", name, poolName)
+		}
+		return nil, fmt.Errorf("failed to demote image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	logger.Infof("demoted image %s in pool %s", name, poolName)
+	return getImageMirroring(context, clusterName, name, poolName)
+}
+
+// CephImageMirrorStatus is the replication status of a mirrored image, as reported by
+// 'rbd mirror image status'. The description includes the replay progress of the image, for
+// example how many journal entries the mirror is behind the primary.
+type CephImageMirrorStatus struct {
+	Name        string `json:"name"`
+	GlobalID    string `json:"global_id"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	LastUpdate  string `json:"last_update"`
+	// Mirroring is whether mirroring is enabled and whether this is the primary image
+	Mirroring CephImageMirroring `json:"-"`
+}
+
+// GetImageMirrorStatus returns the mirroring state and replication status of an image. If mirroring
+// is not enabled on the image, only the mirroring state is set.
+func GetImageMirrorStatus(context *clusterd.Context, clusterName, name, poolName string) (*CephImageMirrorStatus, error) {
+	mirroring, err := getImageMirroring(context, clusterName, name, poolName)
+	if err != nil {
+		return nil, err
+	}
+	if !mirroring.IsEnabled() {
+		return &CephImageMirrorStatus{Name: name, Mirroring: *mirroring}, nil
+	}
+
+	args := []string{"mirror", "image", "status", getImageSpec(name, poolName)}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mirror status of image %s in pool %s: %+v. output: %s", name, poolName, err, string(buf))
+	}
+
+	var status CephImageMirrorStatus
+	if err := json.Unmarshal(buf, &status); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+	status.Mirroring = *mirroring
+	return &status, nil
+}
+
+func getImageMirroring(context *clusterd.Context, clusterName, name, poolName string) (*CephImageMirroring, error) {
+	image, err := GetImageInfo(context, clusterName, name, poolName)
+	if err != nil {
+		return nil, err
+	}
+	if image.Mirroring == nil {
+		return &CephImageMirroring{State: "disabled"}, nil
+	}
+	return image.Mirroring, nil
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageMirroring(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	var mirrorArgs []string
+	mirroring := ""
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "mirror" && args[1] == "image":
+			mirrorArgs = args
+			if args[2] == "enable" {
+				mirroring = `,"mirroring":{"state":"enabled","global_id":"8b1a9c6e-3d2f-4c1b-9a5e-2f7d6c4b3a21","primary":true}`
+			} else {
+				mirroring = ""
+			}
+			return "", nil
+		case command == "rbd" && args[0] == "info":
+			return `{"name":"image1","size":1048576,"format":2,"features":["layering","exclusive-lock","journaling"]` + mirroring + `}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	state, err := EnableImageMirroring(context, "foocluster", cephver.Nautilus, "image1", "pool1", ImageMirrorModeJournal)
+	assert.Nil(t, err)
+	assert.True(t, state.IsEnabled())
+	assert.True(t, state.Primary)
+	// nautilus does not accept a mode
+	assert.Equal(t, []string{"mirror", "image", "enable", "pool1/image1"}, mirrorArgs[:4])
+	assert.NotContains(t, mirrorArgs, ImageMirrorModeJournal)
+
+	state, err = EnableImageMirroring(context, "foocluster", cephver.Octopus, "image1", "pool1", ImageMirrorModeSnapshot)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mirror", "image", "enable", "pool1/image1", ImageMirrorModeSnapshot}, mirrorArgs[:5])
+
+	_, err = EnableImageMirroring(context, "foocluster", cephver.Nautilus, "image1", "pool1", ImageMirrorModeSnapshot)
+	assert.NotNil(t, err)
+	_, err = EnableImageMirroring(context, "foocluster", cephver.Nautilus, "image1", "pool1", "bogus")
+	assert.NotNil(t, err)
+
+	state, err = DisableImageMirroring(context, "foocluster", "image1", "pool1", true)
+	assert.Nil(t, err)
+	assert.False(t, state.IsEnabled())
+	assert.Equal(t, []string{"mirror", "image", "disable", "pool1/image1", "--force"}, mirrorArgs[:5])
+}
+
+func TestGetImageMirrorStatus(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	mirroring := `,"mirroring":{"state":"enabled","global_id":"8b1a9c6e-3d2f-4c1b-9a5e-2f7d6c4b3a21","primary":false}`
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "mirror" && args[1] == "image" && args[2] == "status":
+			assert.Equal(t, "pool1/image1", args[3])
+			return `{"name":"image1","global_id":"8b1a9c6e-3d2f-4c1b-9a5e-2f7d6c4b3a21","state":"up+replaying",` +
+				`"description":"replaying, master_position=[object_number=3, tag_tid=1, entry_tid=3], ` +
+				`mirror_position=[object_number=3, tag_tid=1, entry_tid=3], entries_behind_master=0",` +
+				`"last_update":"2019-06-12 10:05:31"}`, nil
+		case command == "rbd" && args[0] == "info":
+			return `{"name":"image1","size":1048576,"format":2` + mirroring + `}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	status, err := GetImageMirrorStatus(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, "up+replaying", status.State)
+	assert.Equal(t, "2019-06-12 10:05:31", status.LastUpdate)
+	assert.Contains(t, status.Description, "entries_behind_master=0")
+	assert.True(t, status.Mirroring.IsEnabled())
+	assert.False(t, status.Mirroring.Primary)
+
+	// the replication status is not queried when mirroring is disabled
+	mirroring = ""
+	status, err = GetImageMirrorStatus(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.False(t, status.Mirroring.IsEnabled())
+	assert.Equal(t, "", status.State)
+}
+
+func TestPromoteAndDemoteImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	var mirrorArgs []string
+	primary := "false"
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "mirror" && args[1] == "image":
+			mirrorArgs = args
+			if args[2] == "promote" {
+				primary = "true"
+			} else if args[2] == "demote" {
+				primary = "false"
+			}
+			return "", nil
+		case command == "rbd" && args[0] == "info":
+			return `{"name":"image1","size":1048576,"format":2,"mirroring":{"state":"enabled","global_id":"1","primary":` + primary + `}}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	state, err := PromoteImage(context, "foocluster", "image1", "pool1", false)
+	assert.Nil(t, err)
+	assert.True(t, state.Primary)
+	assert.Equal(t, []string{"mirror", "image", "promote", "pool1/image1"}, mirrorArgs[:4])
+	assert.NotContains(t, mirrorArgs, "--force")
+
+	state, err = DemoteImage(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.False(t, state.Primary)
+	assert.Equal(t, []string{"mirror", "image", "demote", "pool1/image1"}, mirrorArgs[:4])
+
+	_, err = PromoteImage(context, "foocluster", "image1", "pool1", true)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mirror", "image", "promote", "pool1/image1", "--force"}, mirrorArgs[:5])
+}
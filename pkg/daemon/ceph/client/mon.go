@@ -98,3 +98,40 @@ func GetMonTimeStatus(context *clusterd.Context, clusterName string) (*MonTimeSt
 
 	return &timeStatus, nil
 }
+
+// MonQuorumStatus represents the response from a quorum_status mon_command (subset of all available
+// fields, only marshal ones we care about)
+type MonQuorumStatus struct {
+	Quorum      []int    `json:"quorum"`
+	QuorumNames []string `json:"quorum_names"`
+	LeaderName  string   `json:"quorum_leader_name"`
+	MonMap      struct {
+		Mons []MonMapEntry `json:"mons"`
+	} `json:"monmap"`
+}
+
+// InQuorum returns whether the mon with the given name is part of the quorum
+func (s *MonQuorumStatus) InQuorum(name string) bool {
+	for _, quorumName := range s.QuorumNames {
+		if quorumName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMonQuorumStatus calls quorum_status mon_command
+func GetMonQuorumStatus(context *clusterd.Context, clusterName string) (*MonQuorumStatus, error) {
+	args := []string{"quorum_status"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("quorum status failed. %+v", err)
+	}
+
+	var status MonQuorumStatus
+	if err := json.Unmarshal(buf, &status); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, buf)
+	}
+
+	return &status, nil
+}
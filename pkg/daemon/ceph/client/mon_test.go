@@ -19,6 +19,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -66,3 +68,29 @@ func TestCephArgs(t *testing.T) {
 	assert.Equal(t, 2, len(args))
 	assert.Equal(t, "myarg", args[0])
 }
+
+func TestGetMonQuorumStatus(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		assert.Equal(t, "quorum_status", args[0])
+		return `{"election_epoch":8,"quorum":[0,1],"quorum_names":["a","b"],"quorum_leader_name":"a",` +
+			`"monmap":{"epoch":3,"mons":[{"rank":0,"name":"a","addr":"10.0.0.1:6789/0"},` +
+			`{"rank":1,"name":"b","addr":"10.0.0.2:6789/0"},{"rank":2,"name":"c","addr":"10.0.0.3:6789/0"}]}}`, nil
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	status, err := GetMonQuorumStatus(context, "mycluster")
+	assert.Nil(t, err)
+	assert.Equal(t, "a", status.LeaderName)
+	assert.Equal(t, []int{0, 1}, status.Quorum)
+	assert.Equal(t, 3, len(status.MonMap.Mons))
+	assert.Equal(t, "10.0.0.3:6789/0", status.MonMap.Mons[2].Address)
+	assert.True(t, status.InQuorum("b"))
+	assert.False(t, status.InQuorum("c"))
+
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		return "", fmt.Errorf("mock failure")
+	}
+	_, err = GetMonQuorumStatus(context, "mycluster")
+	assert.NotNil(t, err)
+}
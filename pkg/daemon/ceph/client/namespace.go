@@ -0,0 +1,86 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// CreateNamespace creates an rbd namespace in a pool. Images in a namespace are isolated from the
+// images in the rest of the pool and are not listed with them. Namespaces are only available since
+// nautilus.
+func CreateNamespace(context *clusterd.Context, clusterName string, cephVersion cephver.CephVersion, poolName, namespace string) error {
+	if !cephVersion.IsAtLeastNautilus() {
+		return fmt.Errorf("rbd namespaces are not supported on ceph version %s", cephVersion.String())
+	}
+	if err := validateName("pool", poolName); err != nil {
+		return err
+	}
+	if err := validateName("namespace", namespace); err != nil {
+		return err
+	}
+
+	spec := getNamespaceSpec(poolName, namespace)
+	args := []string{"namespace", "create", spec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return fmt.Errorf("namespace %s already exists in pool %s", namespace, poolName)
+		}
+		return fmt.Errorf("failed to create namespace %s: %+v. output: %s", spec, err, string(buf))
+	}
+
+	logger.Infof("created namespace %s", spec)
+	return nil
+}
+
+// ListNamespaces lists the rbd namespaces in a pool.
+func ListNamespaces(context *clusterd.Context, clusterName, poolName string) ([]string, error) {
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"namespace", "ls", poolName}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces in pool %s: %+v. output: %s", poolName, err, string(buf))
+	}
+
+	var namespaces []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(buf, &namespaces); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	names := []string{}
+	for _, ns := range namespaces {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
+
+func getNamespaceSpec(poolName, namespace string) string {
+	return fmt.Sprintf("%s/%s", poolName, namespace)
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespaces(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	createCalled := false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "namespace" && args[1] == "create":
+			createCalled = true
+			assert.Equal(t, "pool1/tenant-a", args[2])
+			return "", nil
+		case command == "rbd" && args[0] == "namespace" && args[1] == "ls":
+			assert.Equal(t, "pool1", args[2])
+			return `[{"name":"tenant-a"},{"name":"tenant-b"}]`, nil
+		case command == "rbd" && args[0] == "ls" && args[1] == "-l":
+			assert.Equal(t, "pool1", args[2])
+			assert.Equal(t, "--namespace", args[3])
+			assert.Equal(t, "tenant-a", args[4])
+			return `[{"image":"image1","size":1048576,"format":2}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	err := CreateNamespace(context, "foocluster", cephver.Mimic, "pool1", "tenant-a")
+	assert.NotNil(t, err)
+	assert.False(t, createCalled)
+
+	err = CreateNamespace(context, "foocluster", cephver.Nautilus, "pool1", "tenant-a")
+	assert.Nil(t, err)
+	assert.True(t, createCalled)
+
+	namespaces, err := ListNamespaces(context, "foocluster", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"tenant-a", "tenant-b"}, namespaces)
+
+	images, err := ListImages(context, "foocluster", "pool1", "tenant-a")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(images))
+	assert.Equal(t, "image1", images[0].Name)
+
+	_, err = ListImages(context, "foocluster", "pool1", "tenant/a")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "namespace name")
+}
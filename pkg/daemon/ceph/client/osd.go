@@ -19,8 +19,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/exec"
 )
 
 type OSDUsage struct {
@@ -36,6 +39,7 @@ type OSDUsage struct {
 type OSDNodeUsage struct {
 	ID          int         `json:"id"`
 	Name        string      `json:"name"`
+	DeviceClass string      `json:"device_class"`
 	CrushWeight json.Number `json:"crush_weight"`
 	Depth       json.Number `json:"depth"`
 	Reweight    json.Number `json:"reweight"`
@@ -144,6 +148,53 @@ func OSDOut(context *clusterd.Context, clusterName string, osdID int) (string, e
 	return string(buf), err
 }
 
+// OSDOutSafe marks an OSD out like OSDOut, but only if ceph reports that it is ok to stop, so that no
+// placement group would go below the min_size of its pool.
+func OSDOutSafe(context *clusterd.Context, clusterName string, osdID int) (string, error) {
+	if err := OSDOkToStop(context, clusterName, osdID); err != nil {
+		return "", err
+	}
+	return OSDOut(context, clusterName, osdID)
+}
+
+// OSDOkToStop checks whether an OSD can be stopped or marked out without any placement group going
+// below the min_size of its pool and becoming unavailable.
+func OSDOkToStop(context *clusterd.Context, clusterName string, osdID int) error {
+	args := []string{"osd", "ok-to-stop", strconv.Itoa(osdID)}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EBUSY) {
+			return fmt.Errorf("osd.%d is not ok to stop, pools would go below min_size. output: %s", osdID, string(buf))
+		}
+		return fmt.Errorf("failed to check if osd.%d is ok to stop: %+v. output: %s", osdID, err, string(buf))
+	}
+	return nil
+}
+
+// OSDIn marks an OSD back in after it was marked out, so that data is placed on it again.
+func OSDIn(context *clusterd.Context, clusterName string, osdID int) (string, error) {
+	args := []string{"osd", "in", strconv.Itoa(osdID)}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	return string(buf), err
+}
+
+// BlacklistClient blocks the client at the given address from the cluster. An expire of 0 uses the
+// default blacklist duration of the cluster.
+func BlacklistClient(context *clusterd.Context, clusterName, address string, expire time.Duration) error {
+	args := []string{"osd", "blacklist", "add", address}
+	if expire > 0 {
+		args = append(args, strconv.Itoa(int(expire.Seconds())))
+	}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return fmt.Errorf("failed to blacklist client %s: %+v. output: %s", address, err, string(buf))
+	}
+
+	logger.Infof("blacklisted client %s", address)
+	return nil
+}
+
 func OSDRemove(context *clusterd.Context, clusterName string, osdID int) (string, error) {
 	args := []string{"osd", "rm", strconv.Itoa(osdID)}
 	buf, err := NewCephCommand(context, clusterName, args).Run()
@@ -0,0 +1,98 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOSDOut(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	okToStop := true
+	okToStopCalled := false
+	outCalled := false
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "osd" && args[1] == "ok-to-stop":
+			assert.Equal(t, "1", args[2])
+			okToStopCalled = true
+			if !okToStop {
+				return "Error EBUSY: 12 PGs are already too degraded, would become too degraded or might become unavailable",
+					mockExitError(syscall.EBUSY)
+			}
+			return "OSD(s) 1 are ok to stop without reducing availability or risking data", nil
+		case args[0] == "osd" && args[1] == "out":
+			assert.Equal(t, "1", args[2])
+			outCalled = true
+			return "marked out osd.1.", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// the osd is marked out without checking if it is ok to stop
+	okToStop = false
+	out, err := OSDOut(context, "foocluster", 1)
+	assert.Nil(t, err)
+	assert.True(t, outCalled)
+	assert.False(t, okToStopCalled)
+	assert.Equal(t, "marked out osd.1.", out)
+
+	// the safe variant checks first
+	okToStop = true
+	outCalled = false
+	out, err = OSDOutSafe(context, "foocluster", 1)
+	assert.Nil(t, err)
+	assert.True(t, okToStopCalled)
+	assert.True(t, outCalled)
+	assert.Equal(t, "marked out osd.1.", out)
+
+	// the osd is not marked out if pools would go below min_size
+	okToStop = false
+	outCalled = false
+	_, err = OSDOutSafe(context, "foocluster", 1)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "would go below min_size")
+	assert.False(t, outCalled)
+}
+
+func TestOSDIn(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	inCalled := false
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+		switch {
+		case args[0] == "osd" && args[1] == "in":
+			assert.Equal(t, "1", args[2])
+			inCalled = true
+			return "marked in osd.1.", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	out, err := OSDIn(context, "foocluster", 1)
+	assert.Nil(t, err)
+	assert.True(t, inCalled)
+	assert.Equal(t, "marked in osd.1.", out)
+}
@@ -18,6 +18,7 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -39,6 +40,7 @@ type CephStoragePoolDetails struct {
 	Name               string `json:"pool"`
 	Number             int    `json:"pool_id"`
 	Size               uint   `json:"size"`
+	PGCount            int    `json:"pg_num"`
 	ErasureCodeProfile string `json:"erasure_code_profile"`
 	FailureDomain      string `json:"failureDomain"`
 	CrushRoot          string `json:"crushRoot"`
@@ -52,7 +54,10 @@ type CephStoragePoolStats struct {
 		Stats struct {
 			BytesUsed    float64 `json:"bytes_used"`
 			RawBytesUsed float64 `json:"raw_bytes_used"`
+			// MaxAvail is the number of bytes that can still be stored in the pool. It is computed by
+			// ceph from the pool's replication or erasure coding overhead.
 			MaxAvail     float64 `json:"max_avail"`
+			PercentUsed  float64 `json:"percent_used"`
 			Objects      float64 `json:"objects"`
 			DirtyObjects float64 `json:"dirty"`
 			ReadIO       float64 `json:"rd"`
@@ -63,6 +68,23 @@ type CephStoragePoolStats struct {
 	} `json:"pools"`
 }
 
+// CephStoragePoolQuota is a representation of the json structure returned by 'ceph osd pool get-quota'.
+// A value of 0 means there is no limit.
+type CephStoragePoolQuota struct {
+	Name       string `json:"pool_name"`
+	Number     int    `json:"pool_id"`
+	MaxObjects uint64 `json:"quota_max_objects"`
+	MaxBytes   uint64 `json:"quota_max_bytes"`
+}
+
+// CephStoragePoolQuotaUsage is the quota of a pool along with the bytes and objects currently stored
+// in it, as reported by 'ceph df'.
+type CephStoragePoolQuotaUsage struct {
+	CephStoragePoolQuota
+	BytesUsed uint64
+	Objects   uint64
+}
+
 func ListPoolSummaries(context *clusterd.Context, clusterName string) ([]CephStoragePoolSummary, error) {
 	args := []string{"osd", "lspools"}
 	buf, err := NewCephCommand(context, clusterName, args).Run()
@@ -279,6 +301,59 @@ func SetPoolProperty(context *clusterd.Context, clusterName, name, propName stri
 	return nil
 }
 
+// SetPoolQuota limits the number of bytes and objects that can be stored in a pool. A value of 0
+// removes the limit. The effective quota of the pool is returned with its current usage so the caller
+// can tell whether the pool is already over the new limits. The two limits are set by separate
+// commands, so an error after the byte limit was applied says so.
+func SetPoolQuota(context *clusterd.Context, clusterName, name string, maxBytes, maxObjects uint64) (*CephStoragePoolQuotaUsage, error) {
+	args := []string{"osd", "pool", "set-quota", name, "max_bytes", strconv.FormatUint(maxBytes, 10)}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return nil, fmt.Errorf("failed to set max bytes quota on pool %s. %+v", name, err)
+	}
+
+	args = []string{"osd", "pool", "set-quota", name, "max_objects", strconv.FormatUint(maxObjects, 10)}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return nil, fmt.Errorf("max bytes quota on pool %s was set to %d, but failed to set max objects quota. %+v", name, maxBytes, err)
+	}
+
+	quota, err := GetPoolQuota(context, clusterName, name)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := GetPoolStats(context, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	for _, pool := range stats.Pools {
+		if pool.Name == name {
+			return &CephStoragePoolQuotaUsage{
+				CephStoragePoolQuota: *quota,
+				BytesUsed:            uint64(pool.Stats.BytesUsed),
+				Objects:              uint64(pool.Stats.Objects),
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("pool %s not found in pool stats", name)
+}
+
+// GetPoolQuota gets the byte and object limits of a pool.
+func GetPoolQuota(context *clusterd.Context, clusterName, name string) (*CephStoragePoolQuota, error) {
+	args := []string{"osd", "pool", "get-quota", name}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota of pool %s. %+v", name, err)
+	}
+
+	var quota CephStoragePoolQuota
+	if err := json.Unmarshal(buf, &quota); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	return &quota, nil
+}
+
 func GetPoolStats(context *clusterd.Context, clusterName string) (*CephStoragePoolStats, error) {
 	args := []string{"df", "detail"}
 	buf, err := NewCephCommand(context, clusterName, args).Run()
@@ -348,13 +423,39 @@ func GetPools(context *clusterd.Context, clusterName string) ([]model.Pool, erro
 		}
 		pools[i] = pool
 	}
+
+	// sort the pools by name so the order does not depend on the order the pools were created in
+	sort.Slice(pools, func(i, j int) bool { return pools[i].Name < pools[j].Name })
 	return pools, nil
 }
 
+// GetPoolApplications gets the names of the applications (such as rbd, cephfs or rgw) that are
+// enabled on a pool.
+func GetPoolApplications(context *clusterd.Context, clusterName, name string) ([]string, error) {
+	args := []string{"osd", "pool", "application", "get", name}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applications of pool %s: %+v", name, err)
+	}
+
+	var apps map[string]interface{}
+	if err := json.Unmarshal(buf, &apps); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	names := []string{}
+	for app := range apps {
+		names = append(names, app)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 func cephPoolToModelPool(cephPool CephStoragePoolDetails, ecpDetails map[string]CephErasureCodeProfile) (model.Pool, error) {
 	pool := model.Pool{
-		Name:   cephPool.Name,
-		Number: cephPool.Number,
+		Name:    cephPool.Name,
+		Number:  cephPool.Number,
+		PGCount: cephPool.PGCount,
 	}
 
 	if cephPool.ErasureCodeProfile != "" {
@@ -172,3 +172,108 @@ func testIsStringInSlice(a string, list []string) bool {
 	}
 	return false
 }
+
+func TestGetPools(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+		logger.Infof("Command: %s %v", command, args)
+		if args[0] == "osd" && args[1] == "lspools" {
+			return `[{"poolnum":2,"poolname":"pool2"},{"poolnum":1,"poolname":"pool1"}]`, nil
+		}
+		if args[0] == "osd" && args[1] == "pool" && args[2] == "get" {
+			if args[3] == "pool1" {
+				return `{"pool":"pool1","pool_id":1,"size":3}{"pool":"pool1","pool_id":1,"pg_num":64}`, nil
+			}
+			return `{"pool":"pool2","pool_id":2,"size":1}{"pool":"pool2","pool_id":2,"pg_num":8}`, nil
+		}
+		if args[0] == "osd" && args[1] == "pool" && args[2] == "application" {
+			assert.Equal(t, "get", args[3])
+			return `{"rbd":{},"cephfs":{"data":"myfs"}}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	pools, err := GetPools(context, "myns")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(pools))
+	assert.Equal(t, "pool1", pools[0].Name)
+	assert.Equal(t, 64, pools[0].PGCount)
+	assert.Equal(t, uint(3), pools[0].ReplicatedConfig.Size)
+	assert.Equal(t, "pool2", pools[1].Name)
+	assert.Equal(t, 8, pools[1].PGCount)
+
+	apps, err := GetPoolApplications(context, "myns", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"cephfs", "rbd"}, apps)
+}
+
+func TestSetPoolQuota(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	quotas := map[string]string{}
+	failObjects := false
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+		logger.Infof("Command: %s %v", command, args)
+		if args[0] == "osd" && args[1] == "pool" && args[2] == "set-quota" {
+			assert.Equal(t, "mypool", args[3])
+			if args[4] == "max_objects" && failObjects {
+				return "", fmt.Errorf("mock set-quota failure")
+			}
+			quotas[args[4]] = args[5]
+			return "", nil
+		}
+		if args[0] == "osd" && args[1] == "pool" && args[2] == "get-quota" {
+			assert.Equal(t, "mypool", args[3])
+			return `{"pool_name":"mypool","pool_id":1,"quota_max_objects":0,"quota_max_bytes":10737418240}`, nil
+		}
+		if args[0] == "df" && args[1] == "detail" {
+			return `{"pools":[{"name":"otherpool","id":2,"stats":{"bytes_used":4096,"objects":1}},` +
+				`{"name":"mypool","id":1,"stats":{"bytes_used":1073741824,"objects":256}}]}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	quota, err := SetPoolQuota(context, "myns", "mypool", 10737418240, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "10737418240", quotas["max_bytes"])
+	assert.Equal(t, "0", quotas["max_objects"])
+	assert.Equal(t, uint64(10737418240), quota.MaxBytes)
+	assert.Equal(t, uint64(0), quota.MaxObjects)
+	assert.Equal(t, uint64(1073741824), quota.BytesUsed)
+	assert.Equal(t, uint64(256), quota.Objects)
+
+	// the byte limit is applied when the object limit fails, and the error reports it
+	quotas = map[string]string{}
+	failObjects = true
+	quota, err = SetPoolQuota(context, "myns", "mypool", 2048, 100)
+	assert.NotNil(t, err)
+	assert.Nil(t, quota)
+	assert.Contains(t, err.Error(), "max bytes quota on pool mypool was set to 2048")
+	assert.Equal(t, "2048", quotas["max_bytes"])
+	_, ok := quotas["max_objects"]
+	assert.False(t, ok)
+}
+
+func TestGetPoolStats(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+		logger.Infof("Command: %s %v", command, args)
+		if args[0] == "df" && args[1] == "detail" {
+			return `{"stats":{"total_bytes":32212254720,"total_used_bytes":3221225472,"total_avail_bytes":28991029248},` +
+				`"pools":[{"name":"mypool","id":1,"stats":{"bytes_used":1073741824,"raw_bytes_used":3221225472,` +
+				`"percent_used":0.035,"max_avail":9663676416,"objects":256}}]}`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	stats, err := GetPoolStats(context, "myns")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(stats.Pools))
+	assert.Equal(t, "mypool", stats.Pools[0].Name)
+	assert.Equal(t, float64(1073741824), stats.Pools[0].Stats.BytesUsed)
+	assert.Equal(t, float64(9663676416), stats.Pools[0].Stats.MaxAvail)
+	assert.Equal(t, 0.035, stats.Pools[0].Stats.PercentUsed)
+	assert.Equal(t, float64(256), stats.Pools[0].Stats.Objects)
+}
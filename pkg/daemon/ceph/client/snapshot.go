@@ -0,0 +1,284 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"syscall"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// CephSnapshot is a representation of the json structure returned by 'rbd snap ls'
+type CephSnapshot struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Size      uint64 `json:"size"`
+	Protected string `json:"protected"`
+	Timestamp string `json:"timestamp"`
+}
+
+// IsProtected returns whether the snapshot is protected from deletion
+func (s *CephSnapshot) IsProtected() bool {
+	return s.Protected == "true"
+}
+
+// CreateSnapshot creates a snapshot of a block storage image. The created snapshot is returned with
+// the id, size and timestamp that rbd assigned to it.
+func CreateSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) (*CephSnapshot, error) {
+	if err := validateName("image", imageName); err != nil {
+		return nil, err
+	}
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
+	if err := validateName("snapshot", snapName); err != nil {
+		return nil, err
+	}
+	snapSpec := getSnapSpec(imageName, poolName, snapName)
+	args := []string{"snap", "create", snapSpec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return nil, fmt.Errorf("snapshot %s already exists", snapSpec)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", imageName, poolName)
+		}
+		return nil, fmt.Errorf("failed to create snapshot %s: %+v. output: %s", snapSpec, err, string(buf))
+	}
+
+	logger.Infof("created snapshot %s", snapSpec)
+
+	// 'rbd snap create' does not print the snapshot, so look it up to get the timestamp
+	snapshots, err := ListSnapshots(context, clusterName, imageName, poolName)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		if snapshots[i].Name == snapName {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot %s not found after it was created", snapSpec)
+}
+
+// ListSnapshots lists the snapshots of a block storage image. Listing does not open the image for
+// writing, so it does not contend for the exclusive lock with a client that has the image mapped.
+func ListSnapshots(context *clusterd.Context, clusterName, imageName, poolName string) ([]CephSnapshot, error) {
+	if err := validateImageSpec(imageName, poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"snap", "ls", getImageSpec(imageName, poolName)}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return nil, fmt.Errorf("image %s not found in pool %s", imageName, poolName)
+		}
+		return nil, fmt.Errorf("failed to list snapshots for image %s in pool %s: %+v. output: %s", imageName, poolName, err, string(buf))
+	}
+
+	var snapshots []CephSnapshot
+	if err = json.Unmarshal(buf, &snapshots); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	return snapshots, nil
+}
+
+// DeleteSnapshot removes a snapshot from a block storage image. Protected snapshots must be
+// unprotected before they can be removed.
+func DeleteSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	if imageName == "" || poolName == "" || snapName == "" {
+		return fmt.Errorf("image name, pool name and snapshot name are required to delete a snapshot")
+	}
+	if err := validateSnapSpec(imageName, poolName, snapName); err != nil {
+		return err
+	}
+
+	snapSpec := getSnapSpec(imageName, poolName, snapName)
+	args := []string{"snap", "rm", snapSpec, "--no-progress"}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("snapshot %s not found", snapSpec)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EBUSY) {
+			return fmt.Errorf("snapshot %s is protected and must be unprotected before it is deleted", snapSpec)
+		}
+		return fmt.Errorf("failed to delete snapshot %s: %+v. output: %s", snapSpec, err, string(buf))
+	}
+
+	logger.Infof("deleted snapshot %s", snapSpec)
+	return nil
+}
+
+// RollbackSnapshot reverts a block storage image to the contents of one of its snapshots. All data
+// written to the image since the snapshot was taken is lost, and the call blocks until the rollback
+// has completed, which can take a long time for large images. The rollback is refused while any
+// clients are watching the image, since they would see the data change underneath them.
+func RollbackSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	if err := validateSnapSpec(imageName, poolName, snapName); err != nil {
+		return err
+	}
+	snapSpec := getSnapSpec(imageName, poolName, snapName)
+	watchers, err := GetImageWatchers(context, clusterName, imageName, poolName)
+	if err != nil {
+		return err
+	}
+	if len(watchers) > 0 {
+		return fmt.Errorf("cannot rollback to snapshot %s, image is in use by %d clients: %+v", snapSpec, len(watchers), watchers)
+	}
+
+	args := []string{"snap", "rollback", snapSpec, "--no-progress"}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("snapshot %s not found", snapSpec)
+		}
+		return fmt.Errorf("failed to rollback to snapshot %s: %+v. output: %s", snapSpec, err, string(buf))
+	}
+
+	logger.Infof("rolled back image %s in pool %s to snapshot %s", imageName, poolName, snapName)
+	return nil
+}
+
+// ProtectSnapshot protects a snapshot from deletion so that it can be used as the parent of clones.
+func ProtectSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	if err := validateSnapSpec(imageName, poolName, snapName); err != nil {
+		return err
+	}
+	snapSpec := getSnapSpec(imageName, poolName, snapName)
+	args := []string{"snap", "protect", snapSpec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("snapshot %s not found", snapSpec)
+		}
+		return fmt.Errorf("failed to protect snapshot %s: %+v. output: %s", snapSpec, err, string(buf))
+	}
+
+	return nil
+}
+
+// UnprotectSnapshot removes the protection from a snapshot. This fails while any clones still depend
+// on the snapshot, in which case the names of the dependent images are included in the error.
+func UnprotectSnapshot(context *clusterd.Context, clusterName, imageName, poolName, snapName string) error {
+	if err := validateSnapSpec(imageName, poolName, snapName); err != nil {
+		return err
+	}
+	snapSpec := getSnapSpec(imageName, poolName, snapName)
+	args := []string{"snap", "unprotect", snapSpec}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.ENOENT) {
+			return fmt.Errorf("snapshot %s not found", snapSpec)
+		}
+		if ok && cmdErr.ExitStatus() == int(syscall.EBUSY) {
+			children, childErr := ListSnapshotChildren(context, clusterName, imageName, poolName, snapName)
+			if childErr != nil {
+				logger.Warningf("failed to list children of snapshot %s. %+v", snapSpec, childErr)
+			}
+			return fmt.Errorf("snapshot %s cannot be unprotected while it has dependent clones %v", snapSpec, children)
+		}
+		return fmt.Errorf("failed to unprotect snapshot %s: %+v. output: %s", snapSpec, err, string(buf))
+	}
+
+	return nil
+}
+
+// PurgeSnapshots removes all snapshots of a block storage image so that the image can be deleted.
+// Protected snapshots are unprotected first, which fails if any of them still have clones.
+func PurgeSnapshots(context *clusterd.Context, clusterName, imageName, poolName string) error {
+	snapshots, err := ListSnapshots(context, clusterName, imageName, poolName)
+	if err != nil {
+		return err
+	}
+	for _, snap := range snapshots {
+		if snap.IsProtected() {
+			if err := UnprotectSnapshot(context, clusterName, imageName, poolName, snap.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	imageSpec := getImageSpec(imageName, poolName)
+	args := []string{"snap", "purge", imageSpec, "--no-progress"}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		return fmt.Errorf("failed to purge snapshots of image %s: %+v. output: %s", imageSpec, err, string(buf))
+	}
+
+	logger.Infof("purged %d snapshots of image %s", len(snapshots), imageSpec)
+	return nil
+}
+
+// ListSnapshotChildren lists the clones that depend on a snapshot, in the form pool/image.
+func ListSnapshotChildren(context *clusterd.Context, clusterName, imageName, poolName, snapName string) ([]string, error) {
+	if err := validateSnapSpec(imageName, poolName, snapName); err != nil {
+		return nil, err
+	}
+	snapSpec := getSnapSpec(imageName, poolName, snapName)
+	args := []string{"children", snapSpec}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list children of snapshot %s: %+v. output: %s", snapSpec, err, string(buf))
+	}
+
+	return parseSnapshotChildren(buf)
+}
+
+func parseSnapshotChildren(buf []byte) ([]string, error) {
+	// older versions of rbd return a list of image specs, newer versions return a list of objects
+	var children []string
+	if err := json.Unmarshal(buf, &children); err == nil {
+		return children, nil
+	}
+
+	var childImages []struct {
+		Pool      string `json:"pool"`
+		Namespace string `json:"pool_namespace"`
+		Image     string `json:"image"`
+	}
+	if err := json.Unmarshal(buf, &childImages); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+	children = []string{}
+	for _, c := range childImages {
+		if c.Namespace != "" {
+			children = append(children, fmt.Sprintf("%s/%s/%s", c.Pool, c.Namespace, c.Image))
+		} else {
+			children = append(children, getImageSpec(c.Image, c.Pool))
+		}
+	}
+	return children, nil
+}
+
+func getSnapSpec(imageName, poolName, snapName string) string {
+	return fmt.Sprintf("%s@%s", getImageSpec(imageName, poolName), snapName)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateSnapshot(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	createCalled := false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "snap" && args[1] == "create":
+			createCalled = true
+			assert.Equal(t, "pool1/image1@snap1", args[2])
+			return "", nil
+		case command == "rbd" && args[0] == "snap" && args[1] == "ls":
+			assert.Equal(t, "pool1/image1", args[2])
+			if !createCalled {
+				return `[]`, nil
+			}
+			return `[{"id":4,"name":"snap1","size":1048576,"protected":"false","timestamp":"Wed Jun 12 20:00:05 2019"}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	snapshot, err := CreateSnapshot(context, "foocluster", "image1", "pool1", "snap1")
+	assert.Nil(t, err)
+	assert.True(t, createCalled)
+	assert.Equal(t, 4, snapshot.ID)
+	assert.Equal(t, "snap1", snapshot.Name)
+	assert.Equal(t, "Wed Jun 12 20:00:05 2019", snapshot.Timestamp)
+
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		return "mocked snap create output", fmt.Errorf("some mocked error")
+	}
+	snapshot, err = CreateSnapshot(context, "foocluster", "image1", "pool1", "snap1")
+	assert.NotNil(t, err)
+	assert.Nil(t, snapshot)
+	assert.True(t, strings.Contains(err.Error(), "mocked snap create output"))
+
+	// the image name is validated like the snapshot name
+	snapshot, err = CreateSnapshot(context, "foocluster", "-image1", "pool1", "snap1")
+	assert.NotNil(t, err)
+	assert.Nil(t, snapshot)
+	assert.Contains(t, err.Error(), "image name")
+}
+
+func TestListSnapshots(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "snap" && args[1] == "ls":
+			assert.Equal(t, "pool1/image1", args[2])
+			return `[{"id":4,"name":"snap1","size":1048576,"protected":"false","timestamp":"Tue Jun 11 21:02:40 2019"},` +
+				`{"id":5,"name":"snap2","size":2097152,"protected":"true","timestamp":"Tue Jun 11 21:03:10 2019"}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	snapshots, err := ListSnapshots(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(snapshots))
+	assert.Equal(t, "snap1", snapshots[0].Name)
+	assert.Equal(t, 4, snapshots[0].ID)
+	assert.False(t, snapshots[0].IsProtected())
+	assert.Equal(t, uint64(2097152), snapshots[1].Size)
+	assert.True(t, snapshots[1].IsProtected())
+}
+
+func TestDeleteSnapshot(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+
+	deleteCalled := false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "snap" && args[1] == "rm":
+			deleteCalled = true
+			assert.Equal(t, "pool1/image1@snap1", args[2])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// all of the names are required
+	err := DeleteSnapshot(context, "foocluster", "image1", "pool1", "")
+	assert.NotNil(t, err)
+	assert.False(t, deleteCalled)
+
+	err = DeleteSnapshot(context, "foocluster", "image1", "pool1", "snap1")
+	assert.Nil(t, err)
+	assert.True(t, deleteCalled)
+}
+
+func TestPurgeSnapshots(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	unprotected := []string{}
+	purgeCalled := false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "snap" && args[1] == "ls":
+			return `[{"id":4,"name":"snap1","size":1048576,"protected":"false","timestamp":"Tue Jun 11 21:02:40 2019"},` +
+				`{"id":5,"name":"golden","size":2097152,"protected":"true","timestamp":"Tue Jun 11 21:03:10 2019"}]`, nil
+		case command == "rbd" && args[0] == "snap" && args[1] == "unprotect":
+			unprotected = append(unprotected, args[2])
+			return "", nil
+		case command == "rbd" && args[0] == "snap" && args[1] == "purge":
+			purgeCalled = true
+			assert.Equal(t, "pool1/image1", args[2])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	err := PurgeSnapshots(context, "foocluster", "image1", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pool1/image1@golden"}, unprotected)
+	assert.True(t, purgeCalled)
+
+	// snapshots are not purged when a protected snapshot cannot be unprotected
+	purgeCalled = false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "snap" && args[1] == "ls":
+			return `[{"id":5,"name":"golden","size":2097152,"protected":"true","timestamp":"Tue Jun 11 21:03:10 2019"}]`, nil
+		case command == "rbd" && args[0] == "snap" && args[1] == "purge":
+			purgeCalled = true
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	err = PurgeSnapshots(context, "foocluster", "image1", "pool1")
+	assert.NotNil(t, err)
+	assert.False(t, purgeCalled)
+}
+
+func TestListSnapshotChildren(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	childrenOutput := ""
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "children":
+			assert.Equal(t, "pool1/image1@snap1", args[1])
+			return childrenOutput, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// luminous and mimic format
+	childrenOutput = `["pool1/clone1","pool2/clone2"]`
+	children, err := ListSnapshotChildren(context, "foocluster", "image1", "pool1", "snap1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pool1/clone1", "pool2/clone2"}, children)
+
+	// nautilus format
+	childrenOutput = `[{"pool":"pool1","pool_namespace":"","image":"clone1"},{"pool":"pool2","pool_namespace":"ns1","image":"clone2"}]`
+	children, err = ListSnapshotChildren(context, "foocluster", "image1", "pool1", "snap1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pool1/clone1", "pool2/ns1/clone2"}, children)
+
+	childrenOutput = `[]`
+	children, err = ListSnapshotChildren(context, "foocluster", "image1", "pool1", "snap1")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(children))
+}
+
+func TestRollbackSnapshot(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	rollbackCalled := false
+	watchers := `[]`
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "status":
+			assert.Equal(t, "pool1/image1", args[1])
+			return `{"watchers":` + watchers + `}`, nil
+		case command == "rbd" && args[0] == "snap" && args[1] == "rollback":
+			rollbackCalled = true
+			assert.Equal(t, "pool1/image1@snap1", args[2])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// the image must not be in use
+	watchers = `[{"address":"10.0.0.1:0/3639521972","client":14170,"cookie":18446462598732840961}]`
+	err := RollbackSnapshot(context, "foocluster", "image1", "pool1", "snap1")
+	assert.NotNil(t, err)
+	assert.True(t, strings.Contains(err.Error(), "in use by 1 clients"))
+	assert.False(t, rollbackCalled)
+
+	watchers = `[]`
+	err = RollbackSnapshot(context, "foocluster", "image1", "pool1", "snap1")
+	assert.Nil(t, err)
+	assert.True(t, rollbackCalled)
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/util/exec"
+)
+
+// CephTrashImage is a representation of the json structure returned by 'rbd trash ls -l'
+type CephTrashImage struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Source    string `json:"source"`
+	DeletedAt string `json:"deleted_at"`
+	// Status is either "expired at <time>" or "protected until <time>", the end of the deferment period
+	Status string `json:"status"`
+}
+
+// IsExpired returns whether the deferment period of the trashed image has passed so it can be purged
+func (t *CephTrashImage) IsExpired() bool {
+	return strings.HasPrefix(t.Status, "expired")
+}
+
+// TrashImage moves a block storage image to the rbd trash instead of deleting it, so that it can be
+// restored later. The image cannot be removed from the trash until the delay has passed. Unlike
+// deleting an image, moving it to the trash succeeds while clients are still watching the image.
+// The id of the image in the trash is returned.
+func TrashImage(context *clusterd.Context, clusterName string, cephVersion cephver.CephVersion, name, poolName string, delay time.Duration) (string, error) {
+	// the id is not printed when the image is moved, and the trash may already hold other images with the
+	// same name, so get the id of the image before it is moved
+	image, err := GetImageInfo(context, clusterName, name, poolName)
+	if err != nil {
+		return "", err
+	}
+	if image.ID == "" {
+		return "", fmt.Errorf("cannot move image %s in pool %s to trash, only format 2 images have an id", name, poolName)
+	}
+
+	imageSpec := getImageSpec(name, poolName)
+	args := []string{"trash", "mv", imageSpec}
+	if delay > 0 {
+		if cephVersion.IsLuminous() {
+			args = append(args, "--delay", strconv.Itoa(int(delay.Seconds())))
+		} else {
+			args = append(args, "--expires-at", time.Now().Add(delay).UTC().Format("2006-01-02 15:04:05"))
+		}
+	}
+
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		return "", fmt.Errorf("failed to move image %s to trash: %+v. output: %s", imageSpec, err, string(buf))
+	}
+
+	logger.Infof("moved image %s to trash with id %s", imageSpec, image.ID)
+	return image.ID, nil
+}
+
+// RestoreImage restores an image from the trash of a pool back to a live image. If newName is empty,
+// the image is restored with the name it had when it was moved to the trash.
+func RestoreImage(context *clusterd.Context, clusterName, poolName, id, newName string) (*CephBlockImage, error) {
+	if newName != "" {
+		if err := validateName("image", newName); err != nil {
+			return nil, err
+		}
+	}
+	images, err := ListTrash(context, clusterName, poolName)
+	if err != nil {
+		return nil, err
+	}
+	var trashed *CephTrashImage
+	for i := range images {
+		if images[i].ID == id {
+			trashed = &images[i]
+			break
+		}
+	}
+	if trashed == nil {
+		return nil, fmt.Errorf("image with id %s not found in the trash of pool %s", id, poolName)
+	}
+
+	name := trashed.Name
+	args := []string{"trash", "restore", fmt.Sprintf("%s/%s", poolName, id)}
+	if newName != "" {
+		name = newName
+		args = append(args, "--image", newName)
+	}
+
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		cmdErr, ok := err.(*exec.CommandError)
+		if ok && cmdErr.ExitStatus() == int(syscall.EEXIST) {
+			return nil, fmt.Errorf("cannot restore image with id %s, image %s already exists in pool %s", id, name, poolName)
+		}
+		return nil, fmt.Errorf("failed to restore image with id %s in pool %s: %+v. output: %s", id, poolName, err, string(buf))
+	}
+
+	logger.Infof("restored image %s from the trash of pool %s", name, poolName)
+	return &CephBlockImage{Name: name}, nil
+}
+
+// ListTrash lists the images in the trash of a pool.
+func ListTrash(context *clusterd.Context, clusterName, poolName string) ([]CephTrashImage, error) {
+	if err := validateName("pool", poolName); err != nil {
+		return nil, err
+	}
+	args := []string{"trash", "ls", "-l", poolName}
+	cmd := NewRBDCommand(context, clusterName, args)
+	cmd.JsonOutput = true
+	buf, err := cmd.RunWithTimeout(CmdExecuteTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash for pool %s: %+v. output: %s", poolName, err, string(buf))
+	}
+
+	var images []CephTrashImage
+	if err = json.Unmarshal(buf, &images); err != nil {
+		return nil, fmt.Errorf("unmarshal failed: %+v. raw buffer response: %s", err, string(buf))
+	}
+
+	return images, nil
+}
+
+// PurgeTrash permanently deletes the images in the trash of a pool whose deferment period has passed.
+// Images that are still within their deferment period are kept. The number of purged images is returned.
+func PurgeTrash(context *clusterd.Context, clusterName, poolName string) (int, error) {
+	before, err := ListTrash(context, clusterName, poolName)
+	if err != nil {
+		return 0, err
+	}
+
+	args := []string{"trash", "purge", poolName, "--no-progress"}
+	buf, err := NewRBDCommand(context, clusterName, args).Run()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash for pool %s: %+v. output: %s", poolName, err, string(buf))
+	}
+
+	after, err := ListTrash(context, clusterName, poolName)
+	if err != nil {
+		return 0, err
+	}
+
+	// images may be moved to the trash while it is purged, so only count the expired images that are gone
+	remaining := map[string]bool{}
+	for _, image := range after {
+		remaining[image.ID] = true
+	}
+	purged := 0
+	for _, image := range before {
+		if image.IsExpired() && !remaining[image.ID] {
+			purged++
+		}
+	}
+	logger.Infof("purged %d images from the trash of pool %s", purged, poolName)
+	return purged, nil
+}
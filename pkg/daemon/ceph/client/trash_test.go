@@ -0,0 +1,190 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package client
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	var moveArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "info":
+			switch args[1] {
+			case "pool1/image1":
+				return `{"name":"image1","id":"1c626b8b4567","size":1048576,"format":2}`, nil
+			case "pool1/image2":
+				return `{"name":"image2","id":"1c556b8b4567","size":1048576,"format":2}`, nil
+			case "pool1/luminous":
+				// luminous does not report the id of the image
+				return `{"name":"luminous","size":1048576,"objects":1,"order":20,"object_size":1048576,` +
+					`"block_name_prefix":"rbd_data.10276b8b4567","format":2,"features":["layering"],"flags":[]}`, nil
+			case "pool1/legacy":
+				return `{"name":"legacy","size":1048576,"block_name_prefix":"rb.0.1014.74b0dc51","format":1}`, nil
+			}
+			return "", fmt.Errorf("image not found")
+		case command == "rbd" && args[0] == "trash" && args[1] == "mv":
+			moveArgs = args
+			return "", nil
+		case command == "rbd" && args[0] == "trash" && args[1] == "ls":
+			// an image that was trashed earlier with the same name must not be mistaken for the moved image
+			return `[{"id":"1c4f6b8b4567","name":"image1"},{"id":"1c626b8b4567","name":"image1"}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// no delay
+	id, err := TrashImage(context, "foocluster", cephver.Nautilus, "image2", "pool1", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, "1c556b8b4567", id)
+	assert.Equal(t, []string{"trash", "mv", "pool1/image2"}, moveArgs[:3])
+	assert.NotContains(t, moveArgs, "--delay")
+	assert.NotContains(t, moveArgs, "--expires-at")
+
+	// luminous takes the delay in seconds
+	id, err = TrashImage(context, "foocluster", cephver.Luminous, "image1", "pool1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "1c626b8b4567", id)
+	assert.Equal(t, []string{"trash", "mv", "pool1/image1", "--delay", "3600"}, moveArgs[:5])
+
+	// the id of an image is found from its block name prefix when rbd info does not report it
+	id, err = TrashImage(context, "foocluster", cephver.Luminous, "luminous", "pool1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, "10276b8b4567", id)
+	assert.Equal(t, []string{"trash", "mv", "pool1/luminous", "--delay", "3600"}, moveArgs[:5])
+
+	// newer versions take the time the image expires at
+	_, err = TrashImage(context, "foocluster", cephver.Mimic, "image1", "pool1", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"trash", "mv", "pool1/image1", "--expires-at"}, moveArgs[:4])
+
+	// the image must exist and have an id
+	moveArgs = nil
+	_, err = TrashImage(context, "foocluster", cephver.Nautilus, "image3", "pool1", 0)
+	assert.NotNil(t, err)
+	_, err = TrashImage(context, "foocluster", cephver.Nautilus, "legacy", "pool1", 0)
+	assert.NotNil(t, err)
+	assert.Nil(t, moveArgs)
+}
+
+func TestRestoreImage(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	var restoreArgs []string
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "trash" && args[1] == "restore":
+			restoreArgs = args
+			return "", nil
+		case command == "rbd" && args[0] == "trash" && args[1] == "ls":
+			return `[{"id":"1c4f6b8b4567","name":"image1"}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	// the id must be in the trash
+	image, err := RestoreImage(context, "foocluster", "pool1", "unknown", "")
+	assert.NotNil(t, err)
+	assert.Nil(t, image)
+	assert.Nil(t, restoreArgs)
+
+	// restore with the original name
+	image, err = RestoreImage(context, "foocluster", "pool1", "1c4f6b8b4567", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "image1", image.Name)
+	assert.Equal(t, "pool1/1c4f6b8b4567", restoreArgs[2])
+	assert.NotEqual(t, "--image", restoreArgs[3])
+
+	// restore with a new name
+	image, err = RestoreImage(context, "foocluster", "pool1", "1c4f6b8b4567", "image2")
+	assert.Nil(t, err)
+	assert.Equal(t, "image2", image.Name)
+	assert.Equal(t, []string{"--image", "image2"}, restoreArgs[3:5])
+}
+
+func TestPurgeTrash(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	context := &clusterd.Context{Executor: executor}
+	mockRBDTimeout(executor)
+
+	purged := false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "trash" && args[1] == "purge":
+			assert.Equal(t, "pool1", args[2])
+			purged = true
+			return "", nil
+		case command == "rbd" && args[0] == "trash" && args[1] == "ls":
+			protected := `{"id":"1c556b8b4567","name":"image2","source":"USER","deleted_at":"Wed Jun 12 20:01:13 2019",` +
+				`"status":"protected until Thu Jun 13 20:01:13 2019"}`
+			if purged {
+				return "[" + protected + "]", nil
+			}
+			return `[{"id":"1c4f6b8b4567","name":"image1","source":"USER","deleted_at":"Wed Jun 12 20:00:05 2019",` +
+				`"status":"expired at Wed Jun 12 20:00:05 2019"},` + protected + "]", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+
+	images, err := ListTrash(context, "foocluster", "pool1")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(images))
+	assert.True(t, images[0].IsExpired())
+	assert.False(t, images[1].IsExpired())
+	assert.Equal(t, "Wed Jun 12 20:01:13 2019", images[1].DeletedAt)
+
+	count, err := PurgeTrash(context, "foocluster", "pool1")
+	assert.Nil(t, err)
+	assert.True(t, purged)
+	assert.Equal(t, 1, count)
+
+	// images moved to the trash during the purge are not subtracted from the count
+	purged = false
+	executor.MockExecuteCommandWithOutput = func(debug bool, actionName string, command string, args ...string) (string, error) {
+		switch {
+		case command == "rbd" && args[0] == "trash" && args[1] == "purge":
+			purged = true
+			return "", nil
+		case command == "rbd" && args[0] == "trash" && args[1] == "ls":
+			if purged {
+				return `[{"id":"1c556b8b4567","name":"image2","status":"protected until Thu Jun 13 20:01:13 2019"},` +
+					`{"id":"1c626b8b4567","name":"image3","status":"protected until Thu Jun 13 20:05:00 2019"}]`, nil
+			}
+			return `[{"id":"1c4f6b8b4567","name":"image1","status":"expired at Wed Jun 12 20:00:05 2019"},` +
+				`{"id":"1c486b8b4567","name":"image4","status":"expired at Wed Jun 12 20:00:10 2019"},` +
+				`{"id":"1c556b8b4567","name":"image2","status":"protected until Thu Jun 13 20:01:13 2019"}]`, nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	count, err = PurgeTrash(context, "foocluster", "pool1")
+	assert.Nil(t, err)
+	assert.True(t, purged)
+	assert.Equal(t, 2, count)
+}
@@ -37,6 +37,7 @@ type Pool struct {
 	Name               string                 `json:"poolName"`
 	Number             int                    `json:"poolNum"`
 	Type               PoolType               `json:"type"`
+	PGCount            int                    `json:"pgCount"`
 	FailureDomain      string                 `json:"failureDomain"`
 	CrushRoot          string                 `json:"crushRoot"`
 	DeviceClass        string                 `json:"deviceClass"`
@@ -199,6 +199,10 @@ func TestAddRemoveNode(t *testing.T) {
 			if args[0] == "pg" && args[1] == "dump" {
 				return `[]`, nil
 			}
+			if args[0] == "auth" && args[1] == "get" {
+				assert.Equal(t, "osd.1", args[2])
+				return "", nil
+			}
 			if args[0] == "auth" && args[1] == "del" {
 				assert.Equal(t, "osd.1", args[2])
 				return "", nil
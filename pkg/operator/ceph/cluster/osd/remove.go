@@ -203,11 +203,17 @@ func purgeOSD(context *clusterd.Context, namespace string, id int) error {
 		return fmt.Errorf("failed to remove osd.%d from crush map. %v", id, err)
 	}
 
-	// delete the auth for the OSD
-	err = client.AuthDelete(context, namespace, fmt.Sprintf("osd.%d", id))
+	// delete the auth for the OSD, unless an earlier attempt to purge the OSD already deleted it
+	exists, err := client.AuthExists(context, namespace, fmt.Sprintf("osd.%d", id))
 	if err != nil {
 		return err
 	}
+	if exists {
+		err = client.AuthDelete(context, namespace, fmt.Sprintf("osd.%d", id), false)
+		if err != nil {
+			return err
+		}
+	}
 
 	// delete the OSD from the cluster
 	_, err = client.OSDRemove(context, namespace, id)
@@ -110,7 +110,7 @@ func deleteFilesystem(context *clusterd.Context, cephVersion cephver.CephVersion
 
 	// Permanently remove the filesystem if it was created by rook
 	if len(fs.Spec.DataPools) != 0 {
-		if err := client.RemoveFilesystem(context, fs.Namespace, fs.Name); err != nil {
+		if err := client.RemoveFilesystem(context, fs.Namespace, fs.Name, false); err != nil {
 			return fmt.Errorf("failed to remove filesystem %s: %+v", fs.Name, err)
 		}
 	}
@@ -19,6 +19,7 @@ package object
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
@@ -146,7 +147,9 @@ func getBucketMetadata(c *Context, bucket string) (*ObjectBucketMetadata, bool,
 	return &ObjectBucketMetadata{Owner: s.Data.Owner, CreatedAt: createdAt}, false, nil
 }
 
-func ListBuckets(c *Context) ([]ObjectBucket, error) {
+// ListBuckets returns the buckets of the object store sorted by name. If owner is not empty, only
+// the buckets owned by that user are returned.
+func ListBuckets(c *Context, owner string) ([]ObjectBucket, error) {
 	logger.Infof("Listing buckets")
 
 	stats, err := GetBucketsStats(c)
@@ -161,10 +164,14 @@ func ListBuckets(c *Context) ([]ObjectBucket, error) {
 		if err != nil {
 			return nil, err
 		}
+		if owner != "" && metadata.Owner != owner {
+			continue
+		}
 
 		buckets = append(buckets, ObjectBucket{Name: bucket, ObjectBucketMetadata: ObjectBucketMetadata{Owner: metadata.Owner, CreatedAt: metadata.CreatedAt}, ObjectBucketStats: stat})
 	}
 
+	sort.Sort(ObjectBuckets(buckets))
 	return buckets, nil
 }
 
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package object
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListBuckets(t *testing.T) {
+	owners := map[string]string{"photos": "alice", "backups": "bob", "archive": "alice"}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			logger.Infof("Execute: %s %v", command, args)
+			if args[0] == "bucket" && args[1] == "stats" {
+				return `[{"bucket":"photos","usage":{"rgw.main":{"size":2048,"num_objects":2}}},` +
+					`{"bucket":"backups","usage":{"rgw.main":{"size":1024,"num_objects":1}}},` +
+					`{"bucket":"archive","usage":{}}]`, nil
+			}
+			if args[0] == "metadata" && args[1] == "get" {
+				owner := owners[strings.TrimPrefix(args[2], "bucket:")]
+				return fmt.Sprintf(`{"data":{"owner":"%s","creation_time":"2019-06-12 20:00:05.123456Z"}}`, owner), nil
+			}
+			return "", fmt.Errorf("unexpected radosgw-admin command '%v'", args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	objContext := NewContext(context, "mystore", "mycluster")
+
+	// all buckets are returned sorted by name without an owner
+	buckets, err := ListBuckets(objContext, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(buckets))
+	assert.Equal(t, "archive", buckets[0].Name)
+	assert.Equal(t, "backups", buckets[1].Name)
+	assert.Equal(t, "photos", buckets[2].Name)
+	assert.Equal(t, "bob", buckets[1].Owner)
+	assert.Equal(t, uint64(2048), buckets[2].Size)
+	assert.Equal(t, uint64(2), buckets[2].NumberOfObjects)
+
+	// only the buckets of the owner are returned
+	buckets, err = ListBuckets(objContext, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(buckets))
+	assert.Equal(t, "archive", buckets[0].Name)
+	assert.Equal(t, "photos", buckets[1].Name)
+
+	buckets, err = ListBuckets(objContext, "carol")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(buckets))
+}
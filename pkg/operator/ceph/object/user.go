@@ -19,7 +19,7 @@ package object
 import (
 	"encoding/json"
 	"fmt"
-
+	"strconv"
 	"strings"
 )
 
@@ -174,3 +174,40 @@ func DeleteUser(c *Context, id string) (string, int, error) {
 
 	return result, RGWErrorNone, nil
 }
+
+// SetUserQuota sets and enables the user-scoped quota for the user with the given ID. A negative
+// maxSize or maxObjects leaves that limit unset. If both are negative, the quota is not enabled.
+func SetUserQuota(c *Context, id string, maxSize, maxObjects int64) (int, error) {
+	logger.Infof("Setting quota for user: %s", id)
+
+	if strings.TrimSpace(id) == "" {
+		return RGWErrorBadData, fmt.Errorf("userId cannot be empty")
+	}
+
+	args := []string{"quota", "set", "--quota-scope", "user", "--uid", id}
+	if maxSize >= 0 {
+		args = append(args, "--max-size", strconv.FormatInt(maxSize, 10))
+	}
+	if maxObjects >= 0 {
+		args = append(args, "--max-objects", strconv.FormatInt(maxObjects, 10))
+	}
+
+	result, err := runAdminCommand(c, args...)
+	if err != nil {
+		return RGWErrorUnknown, fmt.Errorf("failed to set quota for user %s: %+v", id, err)
+	}
+	if strings.Contains(result, "no user info saved") {
+		return RGWErrorNotFound, fmt.Errorf("user not found")
+	}
+
+	if maxSize < 0 && maxObjects < 0 {
+		return RGWErrorNone, nil
+	}
+
+	_, err = runAdminCommand(c, "quota", "enable", "--quota-scope", "user", "--uid", id)
+	if err != nil {
+		return RGWErrorUnknown, fmt.Errorf("failed to enable quota for user %s: %+v", id, err)
+	}
+
+	return RGWErrorNone, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package object
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetUserQuota(t *testing.T) {
+	var setArgs []string
+	enabled := false
+	userExists := true
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			logger.Infof("Execute: %s %v", command, args)
+			if args[0] == "quota" && args[1] == "set" {
+				setArgs = args
+				if !userExists {
+					return "could not set quota: no user info saved", nil
+				}
+				return "", nil
+			}
+			if args[0] == "quota" && args[1] == "enable" {
+				enabled = true
+				return "", nil
+			}
+			return "", fmt.Errorf("unexpected radosgw-admin command '%v'", args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	objContext := NewContext(context, "mystore", "mycluster")
+
+	// set both limits
+	code, err := SetUserQuota(objContext, "bob", 1024, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, RGWErrorNone, code)
+	assert.Equal(t, []string{"quota", "set", "--quota-scope", "user", "--uid", "bob", "--max-size", "1024", "--max-objects", "10"}, setArgs[:10])
+	assert.True(t, enabled)
+
+	// the quota is not enabled when no limits are given
+	enabled = false
+	code, err = SetUserQuota(objContext, "bob", -1, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, RGWErrorNone, code)
+	assert.Equal(t, "--rgw-realm=mystore", setArgs[6])
+	assert.False(t, enabled)
+
+	// the user must exist
+	userExists = false
+	code, err = SetUserQuota(objContext, "alice", 1024, -1)
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorNotFound, code)
+	assert.False(t, enabled)
+
+	// the user id is required
+	code, err = SetUserQuota(objContext, "", 1024, -1)
+	assert.NotNil(t, err)
+	assert.Equal(t, RGWErrorBadData, code)
+}
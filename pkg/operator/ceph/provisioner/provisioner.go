@@ -149,7 +149,7 @@ func (p *RookVolumeProvisioner) createVolume(image, pool, dataPool string, clust
 		return nil, fmt.Errorf("image missing required fields (image=%s, pool=%s, clusterNamespace=%s, size=%d)", image, pool, clusterNamespace, size)
 	}
 
-	createdImage, err := ceph.CreateImage(p.context, clusterNamespace, image, pool, dataPool, uint64(size))
+	createdImage, err := ceph.CreateImage(p.context, clusterNamespace, image, pool, "", dataPool, uint64(size), 0, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to create rook block image %s/%s: %v", pool, image, err)
 	}
@@ -109,7 +109,7 @@ func (b *BlockOperation) List(namespace string) ([]BlockImage, error) {
 	// for each pool, get further details about all the images in the pool
 	images := []BlockImage{}
 	for _, p := range pools {
-		cephImages, err := client.ListImages(context, namespace, p.Name)
+		cephImages, err := client.ListImages(context, namespace, p.Name, "")
 		if err != nil {
 			return nil, fmt.Errorf("failed to get images from pool %s: %+v", p.Name, err)
 		}